@@ -10,6 +10,8 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,32 +19,122 @@ import (
 	"github.com/AlexGustafsson/clabbe/internal/discord"
 	"github.com/AlexGustafsson/clabbe/internal/llm"
 	"github.com/AlexGustafsson/clabbe/internal/llm/ollama"
+	"github.com/AlexGustafsson/clabbe/internal/source"
 	"github.com/AlexGustafsson/clabbe/internal/state"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func run(ctx context.Context, state *state.State) error {
+func newLLMClient(config *state.OllamaConfig) (llm.Client, error) {
+	url, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return ollama.NewClient(url, config.Model, nil), nil
+}
+
+// reloadConfig reloads the config and applies the changes that can be made at
+// runtime.
+func reloadConfig(state *state.State, bot *bot.Bot, logLevel *slog.LevelVar) {
+	slog.Info("Reloading config")
+	changes, err := state.ReloadConfig()
+	if err != nil {
+		slog.Error("Failed to reload config - keeping current config", slog.Any("error", err))
+		return
+	}
+
+	logLevel.Set(state.Config().LogLevel)
+
+	if slices.Contains(changes.Applied, "ollama") {
+		llmClient, err := newLLMClient(state.Config().Ollama)
+		if err != nil {
+			slog.Error("Failed to create ollama client", slog.Any("error", err))
+		} else {
+			bot.SetLLM(llmClient)
+		}
+	}
+
+	if len(changes.RequiresRestart) > 0 {
+		slog.Warn("Reloaded config contains changes that require a restart", slog.Any("applied", changes.Applied), slog.Any("requiresRestart", changes.RequiresRestart))
+	} else {
+		slog.Info("Reloaded config", slog.Any("applied", changes.Applied))
+	}
+}
+
+// configFingerprint returns a string identifying the current version of the
+// config files.
+func configFingerprint(configPath string) string {
+	var builder strings.Builder
+	for _, path := range append([]string{configPath}, state.PromptPaths(configPath)...) {
+		info, err := os.Stat(path)
+		if err == nil {
+			fmt.Fprintf(&builder, "%s:%d;", path, info.ModTime().UnixNano())
+		}
+	}
+	return builder.String()
+}
+
+// watchConfig reloads the config whenever SIGHUP is received or the config
+// files are modified.
+func watchConfig(ctx context.Context, state *state.State, bot *bot.Bot, logLevel *slog.LevelVar) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	fingerprint := configFingerprint(state.ConfigPath())
+
+	// Poll the file rather than relying on file system events, as they are
+	// unreliable for mounted config maps and the like
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hangup:
+			reloadConfig(state, bot, logLevel)
+		case <-ticker.C:
+			current := configFingerprint(state.ConfigPath())
+			if current != fingerprint {
+				fingerprint = current
+				reloadConfig(state, bot, logLevel)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func run(ctx context.Context, state *state.State, logLevel *slog.LevelVar) error {
 	var llmClient llm.Client
-	if state.Config.Ollama != nil {
-		url, err := url.Parse(state.Config.Ollama.Endpoint)
+	if state.Config().Ollama != nil {
+		var err error
+		llmClient, err = newLLMClient(state.Config().Ollama)
 		if err != nil {
 			slog.Error("Failed to parse ollama URL", slog.Any("error", err))
 			return err
 		}
+	}
 
-		llmClient = ollama.NewClient(url, state.Config.Ollama.Model, nil)
+	sources, err := source.FromConfig(state.Config)
+	if err != nil {
+		slog.Error("Failed to configure sources", slog.Any("error", err))
+		return err
 	}
 
-	bot := bot.New(state, llmClient)
+	go sources.Run(ctx)
+
+	bot := bot.New(state, llmClient, sources)
 	var conn *discord.Conn
 
-	if state.Config.Prometheus.Enabled {
+	go watchConfig(ctx, state, bot, logLevel)
+
+	if state.Config().Prometheus.Enabled {
 		if err := prometheus.DefaultRegisterer.Register(state.Metrics); err != nil {
 			return err
 		}
 
-		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", state.Config.Prometheus.Port))
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", state.Config().Prometheus.Port))
 		if err != nil {
 			return err
 		}
@@ -86,7 +178,6 @@ func run(ctx context.Context, state *state.State) error {
 
 	// Connect to Discord
 	slog.Info("Connecting bot to Discord")
-	var err error
 	conn, err = discord.Dial(state, bot)
 	if err != nil {
 		slog.Error("Failed to start bot", slog.Any("error", err))
@@ -102,34 +193,58 @@ func run(ctx context.Context, state *state.State) error {
 }
 
 func main() {
+	var logLevel slog.LevelVar
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: &logLevel,
 	})))
 
 	config := flag.String("config", "", "path to config directory")
+	var logLevelFlag slog.Level
+	flag.TextVar(&logLevelFlag, "log-level", slog.LevelInfo, "log level. Overrides config")
+	prometheusEnabled := flag.Bool("prometheus", false, "enable prometheus support. Overrides config")
+	prometheusPort := flag.Uint("prometheus-port", 8080, "prometheus port. Overrides config")
 	flag.Parse()
 
+	// Flags take precedence over the config file and environment variables, but
+	// only if they're explicitly set
+	overrides := func(config *state.Config) {
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "log-level":
+				config.LogLevel = logLevelFlag
+			case "prometheus":
+				if config.Prometheus == nil {
+					config.Prometheus = &state.PrometheusConfig{}
+				}
+				config.Prometheus.Enabled = *prometheusEnabled
+			case "prometheus-port":
+				if config.Prometheus == nil {
+					config.Prometheus = &state.PrometheusConfig{}
+				}
+				config.Prometheus.Port = uint16(*prometheusPort)
+			}
+		})
+	}
+
 	if *config == "" {
 		slog.Error("Missing required flag config")
 		os.Exit(1)
 	}
 
-	state, err := state.LoadOrInit(*config)
+	state, err := state.LoadOrInit(*config, overrides)
 	if err != nil {
 		slog.Error("Failed to load state", slog.Any("error", err))
 		os.Exit(1)
 	}
 
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-		Level: state.Config.LogLevel,
-	})))
+	logLevel.Set(state.Config().LogLevel)
 
-	if state.Config.DiscordBotToken == "" {
-		slog.Error("Missing required config for Discord bot token")
+	if err := state.Config().Validate(); err != nil {
+		slog.Error("Invalid config", slog.Any("error", err))
 		os.Exit(1)
 	}
 
-	if state.Config.Ollama == nil {
+	if state.Config().Ollama == nil {
 		slog.Warn("Missing ollama config - disabling advanced features")
 	}
 
@@ -169,7 +284,7 @@ func main() {
 		}
 	}()
 
-	err = run(ctx, state)
+	err = run(ctx, state, &logLevel)
 	slog.Debug("Storing state before exiting")
 	if err := state.Store(); err != nil {
 		slog.Error("Failed to store state on exit", slog.Any("error", err))
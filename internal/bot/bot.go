@@ -6,20 +6,24 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"strings"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AlexGustafsson/clabbe/internal/llm"
+	"github.com/AlexGustafsson/clabbe/internal/source"
 	"github.com/AlexGustafsson/clabbe/internal/state"
-	"github.com/AlexGustafsson/clabbe/internal/webm"
-	"github.com/AlexGustafsson/clabbe/internal/youtube"
 	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
 )
 
 var (
 	ErrNoStreamPlaying       = errors.New("no stream is playing")
 	ErrUnsupportedAudioCodec = errors.New("unsupported audio codec")
+	ErrQueueLimitReached     = errors.New("queue limit reached")
+	ErrVideoNotFound         = errors.New("video not found")
+	ErrUnsupportedSource     = errors.New("unsupported source")
+	ErrStationNotFound       = errors.New("station not found")
 )
 
 type ExtrapolationType int
@@ -28,52 +32,121 @@ const (
 	ExtrapolationTypeNone ExtrapolationType = iota << 1
 	ExtrapolationTypeHistory
 	ExtrapolationTypeSuggest
+	// ExtrapolationTypeRecommended queues songs YouTube recommends for recently
+	// played songs. Used when no LLM is configured.
+	ExtrapolationTypeRecommended
 )
 
 type Bot struct {
 	ExtrapolationType ExtrapolationType
+	// Score scores search results to pick the best match of a search. Nil
+	// means the results are scored using the configured weights.
+	Score ScoreFunc
 
 	state *state.State
 
-	llm llm.Client
+	// llmMutex guards llm, which may be replaced when the config is reloaded.
+	llmMutex sync.RWMutex
+	llm      llm.Client
+
+	sources *source.Registry
 
 	mutex        sync.Mutex
 	shouldPlay   bool
 	currentEntry *state.PlaylistEntry
+	// guildID is the id of the guild the bot is currently playing in.
+	guildID string
 
 	isStreaming  bool
 	cancelStream context.CancelFunc
-}
 
-func New(state *state.State, llm llm.Client) *Bot {
-	extrapolationType := ExtrapolationTypeNone
-	if state.Config.ExtrapolateWhenEmpty {
-		extrapolationType = ExtrapolationTypeHistory
-	}
+	// resumed is closed when playback is resumed. Nil when not paused.
+	resumed chan struct{}
+	// position is the playback position of the current entry.
+	position atomic.Int64
+	repeat   bool
+	// streamTitle is the title of the song played by the current entry, if
+	// it's a live stream such as a radio station.
+	streamTitle string
+
+	// restarting is true if the current entry is being restarted.
+	restarting bool
+	// restartAt is the position to restart the current entry at.
+	restartAt time.Duration
+}
 
+func New(state *state.State, llm llm.Client, sources *source.Registry) *Bot {
 	return &Bot{
-		ExtrapolationType: extrapolationType,
+		ExtrapolationType: defaultExtrapolationType(state.Config(), llm),
 
 		state: state,
 
 		llm: llm,
+
+		sources: sources,
 	}
 }
 
+// defaultExtrapolationType returns the configured way of extrapolating. The
+// history is used when an LLM is configured, recommendations otherwise.
+func defaultExtrapolationType(config *state.Config, llm llm.Client) ExtrapolationType {
+	if !config.ExtrapolateWhenEmpty {
+		return ExtrapolationTypeNone
+	}
+
+	if llm == nil {
+		return ExtrapolationTypeRecommended
+	}
+
+	return ExtrapolationTypeHistory
+}
+
+type SearchOptions struct {
+	// UseAI defaults to false.
+	UseAI bool
+	// Requester is the entity that requested the search.
+	Requester state.Entity
+	// Guild is the name of the guild the search was requested in, if any.
+	Guild string
+	// GuildID is the id of the guild the search was requested in, used to look
+	// up its settings.
+	GuildID string
+}
+
 // Search performs a search for content.
-// Returns the first result. When using AI, returns the first result for each
-// additional query provided by the AI.
-func (b *Bot) Search(ctx context.Context, query string, useAI bool) ([]youtube.SearchResult, error) {
-	slog.Debug("Performing search", slog.String("query", query), slog.Bool("useAi", useAI))
+// Livestreams, shorts and compilations are filtered out, and the best scored
+// of the top results is returned. When using AI, returns the best result for
+// each additional query provided by the AI.
+func (b *Bot) Search(ctx context.Context, query string, options *SearchOptions) ([]source.Track, error) {
+	if options == nil {
+		options = &SearchOptions{}
+	}
+
+	slog.Debug("Performing search", slog.String("query", query), slog.Bool("useAi", options.UseAI))
+
+	// Links are resolved as-is
+	if tracks, ok, err := b.sources.Resolve(ctx, query); ok {
+		return tracks, err
+	}
+
 	queries := make([]string, 0)
 
-	if useAI && b.llm != nil {
+	if llmClient := b.llmClient(); options.UseAI && llmClient != nil {
 		slog.Debug("Extrapolating search using AI", slog.String("query", query))
-		res, err := b.llm.Chat(ctx, &llm.ChatRequest{
+		prompt, err := b.songSuggestionPrompt(PromptData{
+			History:   b.state.History.PeakBackN(b.state.Guilds.Settings(options.GuildID).Lookback(b.state.Config())),
+			Requester: options.Requester,
+			Guild:     options.Guild,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := llmClient.Chat(ctx, &llm.ChatRequest{
 			Messages: []llm.Message{
 				{
 					Role:    llm.RoleSystem,
-					Content: b.state.Config.Prompt,
+					Content: prompt,
 				},
 				{
 					Role:    llm.RoleUser,
@@ -87,22 +160,16 @@ func (b *Bot) Search(ctx context.Context, query string, useAI bool) ([]youtube.S
 
 		if len(res.Message.Content) == 0 {
 			slog.Debug("No response from LLM")
-			return []youtube.SearchResult{}, nil
+			return []source.Track{}, nil
 		}
 
 		if res.Message.Content == "no results" {
 			slog.Debug("No results from LLM")
-			return []youtube.SearchResult{}, nil
+			return []source.Track{}, nil
 		}
 		slog.Debug("Got response from AI", slog.String("response", res.Message.Content))
 
-		// TODO: Assume default prompt for now
-		entries := strings.Split(res.Message.Content, "\n")
-		for _, entry := range entries {
-			// The default output has indexes, remove them
-			_, query, _ := strings.Cut(entry, " ")
-			queries = append(queries, query)
-		}
+		queries = parseList(res.Message.Content)
 	} else {
 		// Use query verbatim as use of AI was not requested
 		queries = []string{query}
@@ -110,56 +177,117 @@ func (b *Bot) Search(ctx context.Context, query string, useAI bool) ([]youtube.S
 
 	slog.Debug("Searching for results on YouTube", slog.Any("queries", queries))
 
-	allResults := make([]youtube.SearchResult, 0)
+	allResults := make([]source.Track, 0)
 	for _, query := range queries {
-		results, err := youtube.Search(ctx, query)
+		result, ok, err := b.searchBest(ctx, query)
 		if err != nil {
 			return nil, err
 		}
 
-		if len(results) > 0 {
-			allResults = append(allResults, results[0])
+		if ok {
+			allResults = append(allResults, result)
 		}
 	}
 
 	return allResults, nil
 }
 
+// scoreFunc returns the function used to score search results.
+func (b *Bot) scoreFunc() ScoreFunc {
+	if b.Score != nil {
+		return b.Score
+	}
+
+	return ScoreWith(b.state.Config().Scoring)
+}
+
+// searchBest returns the best result of the first provider with any results.
+func (b *Bot) searchBest(ctx context.Context, query string) (source.Track, bool, error) {
+	for _, provider := range b.sources.Providers() {
+		results, err := provider.Search(ctx, query, searchCandidates)
+		if err != nil {
+			return source.Track{}, false, err
+		}
+
+		if result, ok := bestSearchResult(query, results, b.scoreFunc()); ok {
+			return result, true, nil
+		}
+	}
+
+	return source.Track{}, false, nil
+}
+
 type QueueOptions struct {
 	// UseAI defaults to false.
 	UseAI bool
+	// Guild is the name of the guild the song was queued in, if any.
+	Guild string
+	// GuildID is the id of the guild the song was queued in, used to look up
+	// its settings.
+	GuildID string
+	// MaxPerUser is the maximum number of entries a user may have in the queue.
+	// Defaults to 0, meaning no limit.
+	MaxPerUser int
+	// SkipInspection skips resolving the metadata of songs queued by users up
+	// front, such as when queueing many songs at once. Songs are still checked
+	// using what's already known about them.
+	SkipInspection bool
 }
 
 // Queue performs a search for content and adds the top result to the playlist.
+// Returns ErrQueueLimitReached if the entity has reached the limit set in the
+// options. A single result queued by a user is inspected up front, returning
+// ErrLivestream, ErrAgeRestricted, ErrTooLong or ErrUnavailable if it can't be
+// played. Otherwise, results that can't be played are left out.
 func (b *Bot) Queue(ctx context.Context, query string, addedBy state.Entity, options *QueueOptions) ([]state.PlaylistEntry, error) {
 	slog.Debug("Queueing", slog.String("query", query))
 	if options == nil {
 		options = &QueueOptions{}
 	}
 
-	useAI := options.UseAI
+	remaining := -1
+	if options.MaxPerUser > 0 {
+		remaining = options.MaxPerUser - b.queuedBy(addedBy)
+		if remaining <= 0 {
+			return nil, ErrQueueLimitReached
+		}
+	}
 
-	results, err := b.Search(ctx, query, useAI)
+	results, err := b.Search(ctx, query, &SearchOptions{
+		UseAI:     options.UseAI,
+		Requester: addedBy,
+		Guild:     options.Guild,
+		GuildID:   options.GuildID,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if len(results) == 1 && addedBy.Role != state.RoleSystem && !options.SkipInspection {
+		if err := b.inspectTrack(ctx, results[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		results = slices.DeleteFunc(results, func(result source.Track) bool {
+			return b.checkTrack(result) != nil
+		})
+	}
+
+	if remaining >= 0 && len(results) > remaining {
+		results = results[:remaining]
+	}
+
 	entries := make([]state.PlaylistEntry, len(results))
 
 	if len(results) > 0 {
 		slog.Debug("Got results to queue", slog.Any("results", results))
 		b.mutex.Lock()
 		for i, result := range results {
-			entry := state.PlaylistEntry{
-				Time:    time.Now(),
-				Title:   result.Title,
-				AddedBy: addedBy,
-				Source:  state.SourceYouTube,
-				URI:     result.ID,
-			}
+			entry := result.Entry(addedBy)
 			entries[i] = entry
 			b.state.Queue.AddEntry(entry)
 		}
+		b.interruptLiveStream(entries)
 		b.mutex.Unlock()
 	} else {
 		slog.Debug("No results")
@@ -168,15 +296,138 @@ func (b *Bot) Queue(ctx context.Context, query string, addedBy state.Entity, opt
 	return entries, nil
 }
 
+// QueueResult adds a specific track to the playlist.
+// Returns ErrQueueLimitReached if the entity has reached the limit set in the
+// options. Like Queue, tracks queued by users are inspected up front.
+func (b *Bot) QueueResult(ctx context.Context, result source.Track, addedBy state.Entity, options *QueueOptions) (state.PlaylistEntry, error) {
+	slog.Debug("Queueing result", slog.String("uri", result.URI))
+	if options == nil {
+		options = &QueueOptions{}
+	}
+
+	if options.MaxPerUser > 0 && b.queuedBy(addedBy) >= options.MaxPerUser {
+		return state.PlaylistEntry{}, ErrQueueLimitReached
+	}
+
+	if addedBy.Role != state.RoleSystem && !options.SkipInspection {
+		if err := b.inspectTrack(ctx, result); err != nil {
+			return state.PlaylistEntry{}, err
+		}
+	} else if err := b.checkTrack(result); err != nil {
+		return state.PlaylistEntry{}, err
+	}
+
+	entry := result.Entry(addedBy)
+	b.mutex.Lock()
+	b.state.Queue.AddEntry(entry)
+	b.interruptLiveStream([]state.PlaylistEntry{entry})
+	b.mutex.Unlock()
+
+	return entry, nil
+}
+
+// QueueVideo looks up a YouTube video by its id and adds it to the playlist.
+// Returns ErrVideoNotFound if the video could not be found.
+func (b *Bot) QueueVideo(ctx context.Context, id string, addedBy state.Entity, options *QueueOptions) (state.PlaylistEntry, error) {
+	provider, ok := b.sources.Provider(state.SourceYouTube)
+	if !ok {
+		return state.PlaylistEntry{}, ErrUnsupportedSource
+	}
+
+	result, err := provider.Metadata(ctx, id)
+	if err == source.ErrNotFound {
+		return state.PlaylistEntry{}, ErrVideoNotFound
+	} else if err != nil {
+		return state.PlaylistEntry{}, err
+	}
+
+	return b.QueueResult(ctx, result, addedBy, options)
+}
+
+// Radio adds a radio station to the playlist. The station is either the name
+// of a configured station or the URL of a stream. Returns ErrStationNotFound
+// if there is no such station.
+func (b *Bot) Radio(ctx context.Context, station string, addedBy state.Entity, options *QueueOptions) (state.PlaylistEntry, error) {
+	provider, ok := b.sources.Provider(state.SourceRadio)
+	if !ok {
+		return state.PlaylistEntry{}, ErrUnsupportedSource
+	}
+
+	uri := station
+	name := ""
+	if config, ok := b.state.Config().Station(station); ok {
+		uri = config.URL
+		name = config.Name
+	}
+
+	result, err := provider.Metadata(ctx, uri)
+	if err == source.ErrNotFound || err == source.ErrNotRadio {
+		return state.PlaylistEntry{}, ErrStationNotFound
+	} else if err != nil {
+		return state.PlaylistEntry{}, err
+	}
+
+	if name != "" {
+		result.Title = name
+	}
+
+	return b.QueueResult(ctx, result, addedBy, options)
+}
+
+// interruptLiveStream stops the currently playing live stream, if any, in
+// order to play the added entries. The stream is put back after them, unless
+// it's replaced by another stream. The caller must hold the mutex.
+func (b *Bot) interruptLiveStream(added []state.PlaylistEntry) {
+	if !b.isStreaming || b.currentEntry == nil || b.currentEntry.Source != state.SourceRadio {
+		return
+	}
+
+	replaced := false
+	for _, entry := range added {
+		if entry.Source == state.SourceRadio {
+			replaced = true
+		}
+	}
+
+	slog.Debug("Interrupting live stream", slog.String("uri", b.currentEntry.URI), slog.Bool("replaced", replaced))
+	if !replaced {
+		b.state.Queue.AddEntry(*b.currentEntry)
+	}
+	b.cancelStream()
+}
+
+// queuedBy returns the number of queued entries added by the entity.
+func (b *Bot) queuedBy(entity state.Entity) int {
+	count := 0
+	for _, entry := range b.state.Queue.Entries() {
+		if entry.AddedBy.Role == entity.Role && entry.AddedBy.ID == entity.ID {
+			count++
+		}
+	}
+	return count
+}
+
 type SuggestOptions struct {
-	// UseAI defaults to false.
-	UseAI bool
+	// Guild is the name of the guild the suggestion was made in, if any.
+	Guild string
+	// GuildID is the id of the guild the suggestion was made in, used to look
+	// up its settings.
+	GuildID string
 }
 
 // Suggest adds the results as a basis for songs to play when interpolating.
-func (b *Bot) Suggest(ctx context.Context, addedBy state.Entity, query string) ([]state.PlaylistEntry, error) {
+func (b *Bot) Suggest(ctx context.Context, addedBy state.Entity, query string, options *SuggestOptions) ([]state.PlaylistEntry, error) {
 	slog.Debug("Adding suggestions", slog.String("query", query))
-	results, err := b.Search(ctx, query, true)
+	if options == nil {
+		options = &SuggestOptions{}
+	}
+
+	results, err := b.Search(ctx, query, &SearchOptions{
+		UseAI:     true,
+		Requester: addedBy,
+		Guild:     options.Guild,
+		GuildID:   options.GuildID,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -186,13 +437,7 @@ func (b *Bot) Suggest(ctx context.Context, addedBy state.Entity, query string) (
 	slog.Debug("Got results to add to suggestions", slog.Any("results", results))
 	b.mutex.Lock()
 	for i, result := range results {
-		entry := state.PlaylistEntry{
-			Time:    time.Now(),
-			Title:   result.Title,
-			AddedBy: addedBy,
-			Source:  state.SourceYouTube,
-			URI:     result.ID,
-		}
+		entry := result.Entry(addedBy)
 		entries[i] = entry
 		b.state.Suggestions.AddEntry(entry)
 	}
@@ -201,28 +446,41 @@ func (b *Bot) Suggest(ctx context.Context, addedBy state.Entity, query string) (
 	return entries, nil
 }
 
+// SuggestResult adds a specific track as a basis for songs to play
+// when interpolating.
+func (b *Bot) SuggestResult(result source.Track, addedBy state.Entity) state.PlaylistEntry {
+	slog.Debug("Adding result to suggestions", slog.String("uri", result.URI))
+
+	entry := result.Entry(addedBy)
+	b.mutex.Lock()
+	b.state.Suggestions.AddEntry(entry)
+	b.mutex.Unlock()
+
+	return entry
+}
+
 // Extrapolate adds some entries to the playlist based on suggestions and
-// history.
-func (b *Bot) Extrapolate(ctx context.Context) error {
+// history, or YouTube's recommendations. Returns the added entries.
+func (b *Bot) Extrapolate(ctx context.Context) ([]state.PlaylistEntry, error) {
 	// If possible, use the suggestions immediately
 	b.mutex.Lock()
 	suggestions := b.state.Suggestions.PopN(5)
 	if len(suggestions) > 0 {
 		slog.Debug("There were unused suggestions, using them first")
-		for _, suggestion := range suggestions {
-			suggestion.AddedBy = state.Entity{
+		for i := range suggestions {
+			suggestions[i].AddedBy = state.Entity{
 				Role: state.RoleSystem,
 			}
-			b.state.Queue.Push(suggestion)
+			b.state.Queue.Push(suggestions[i])
 		}
 		b.mutex.Unlock()
-		return nil
+		return suggestions, nil
 	}
 
-	// If no suggestions were ready, extrapolate using AI
-	if b.llm == nil {
+	// Without an LLM, fall back to YouTube's recommendations
+	if b.llmClient() == nil || b.ExtrapolationType == ExtrapolationTypeRecommended {
 		b.mutex.Unlock()
-		return fmt.Errorf("missing required Open AI client")
+		return b.extrapolateWithRecommendations(ctx)
 	}
 
 	// If auto play is on, suggest themes to itself
@@ -234,30 +492,46 @@ func (b *Bot) Extrapolate(ctx context.Context) error {
 	}
 }
 
-func (b *Bot) extrapolateWithThemeSuggestions(ctx context.Context) error {
+func (b *Bot) extrapolateWithThemeSuggestions(ctx context.Context) ([]state.PlaylistEntry, error) {
 	slog.Debug("Extrapolating songs based on suggestions of new themes")
-	res, err := b.llm.Chat(ctx, NewThemeRequest())
+	prompt, err := b.themeSuggestionPrompt(PromptData{})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	res, err := b.llmClient().Chat(ctx, &llm.ChatRequest{
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: prompt,
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: "suggest themes",
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	if len(res.Message.Content) == 0 {
 		slog.Debug("No response from LLM")
-		return nil
+		return nil, nil
 	}
 
 	response := res.Message.Content
 	if response == "no results" {
 		slog.Debug("No results from LLM")
-		return nil
+		return nil, nil
 	}
 	slog.Debug("Got response from Open AI", slog.String("response", response))
 
-	suggestions := strings.Split(response, "\n")
+	suggestions := parseList(response)
 	for _, suggestion := range suggestions {
-		entries, err := b.Suggest(ctx, state.Entity{Role: state.RoleSystem}, suggestion)
+		entries, err := b.Suggest(ctx, state.Entity{Role: state.RoleSystem}, suggestion, nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// For now, just use the first theme that returns results as the suggestion
@@ -267,28 +541,28 @@ func (b *Bot) extrapolateWithThemeSuggestions(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (b *Bot) extrapolateWithHistory(ctx context.Context) error {
-	var lookback strings.Builder
-	entries := b.state.History.PeakBackN(b.state.Config.ExtrapolationLookback)
-	for i, entry := range entries {
-		fmt.Fprintf(&lookback, "%d. %s\n", i+1, entry.Title)
-	}
+func (b *Bot) extrapolateWithHistory(ctx context.Context) ([]state.PlaylistEntry, error) {
+	history := b.state.History.PeakBackN(b.settings().Lookback(b.state.Config()))
 	// TODO: It's ugly to unlock here when it was locked elsewhere (Extrapolate)
 	b.mutex.Unlock()
 
-	slog.Debug("Extrapolating songs based on history", slog.String("history", lookback.String()))
-	res, err := b.llm.Chat(ctx, &llm.ChatRequest{
+	prompt, err := b.songSuggestionPrompt(PromptData{
+		History: history,
+		Similar: similarSongs(ctx, history),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Extrapolating songs based on history", slog.Int("history", len(history)))
+	res, err := b.llmClient().Chat(ctx, &llm.ChatRequest{
 		Messages: []llm.Message{
 			{
 				Role:    llm.RoleSystem,
-				Content: b.state.Config.Prompt,
-			},
-			{
-				Role:    llm.RoleAssistant,
-				Content: lookback.String(),
+				Content: prompt,
 			},
 			{
 				Role:    llm.RoleUser,
@@ -297,43 +571,51 @@ func (b *Bot) extrapolateWithHistory(ctx context.Context) error {
 		},
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(res.Message.Content) == 0 {
 		slog.Debug("No response from LLM")
-		return nil
+		return nil, nil
 	}
 
 	if res.Message.Content == "no results" {
 		slog.Debug("No results from LLM")
-		return nil
+		return nil, nil
 	}
 	slog.Debug("Got response from Open AI", slog.String("response", res.Message.Content))
 
-	// TODO: Assume default prompt for now
-	lines := strings.Split(res.Message.Content, "\n")
-	for _, line := range lines {
-		_, query, _ := strings.Cut(line, " ")
+	added := make([]state.PlaylistEntry, 0)
+	for _, query := range parseList(res.Message.Content) {
 		entity := state.Entity{
 			Role: state.RoleSystem,
 		}
-		if _, err := b.Queue(ctx, strings.TrimSpace(query), entity, nil); err != nil {
-			return err
+		entries, err := b.Queue(ctx, query, entity, nil)
+		if err != nil {
+			return added, err
 		}
+		added = append(added, entries...)
 	}
 
-	return nil
+	return added, nil
 }
 
-// Play starts playing content, sending windows of OPUS-encoded audio to the
-// provided channel.
-func (b *Bot) Play(opus chan<- []byte, songs chan<- string) error {
+// Play starts playing content in the guild, sending windows of OPUS-encoded
+// audio to the provided channel. Playback events are sent to events, if
+// non-nil.
+func (b *Bot) Play(guildID string, opus chan<- []byte, events EventSink) error {
 	if b.isStreaming {
 		return fmt.Errorf("already playing")
 	}
 
+	if events == nil {
+		events = nopEventSink{}
+	}
+
+	b.mutex.Lock()
 	b.shouldPlay = true
+	b.guildID = guildID
+	b.mutex.Unlock()
 
 	failures := 0
 
@@ -343,89 +625,169 @@ func (b *Bot) Play(opus chan<- []byte, songs chan<- string) error {
 		b.mutex.Unlock()
 
 		if !ok {
-			if b.LLMEnabled() && b.state.Config.ExtrapolateWhenEmpty {
+			if b.settings().ShouldExtrapolate(b.state.Config()) {
 				slog.Debug("Playlist is empty, extrapolating")
-				err := b.Extrapolate(context.Background())
+				entries, err := b.Extrapolate(context.Background())
+				if len(entries) > 0 {
+					events.ExtrapolationAdded(entries)
+				}
 				if err != nil {
 					return err
 				}
+
+				// Nothing left to play
+				if len(entries) == 0 {
+					slog.Debug("Nothing to extrapolate, closing")
+					events.QueueEmpty()
+					return nil
+				}
 				continue
 			} else {
 				slog.Debug("Playlist is empty, closing")
+				events.QueueEmpty()
 				return nil
 			}
 		}
 
-		songs <- entry.Title
-		err := b.playOnce(entry, opus)
+		b.mutex.Lock()
+		offset := b.restartAt
+		restarted := b.restarting
+		b.restartAt = 0
+		b.restarting = false
+		b.mutex.Unlock()
+
+		if !restarted {
+			events.TrackStarted(entry)
+		}
+		err := b.playOnce(entry, opus, offset, events)
+
+		b.mutex.Lock()
+		restarting := b.restarting
+		b.mutex.Unlock()
+		if restarting {
+			// The entry was put back in the queue by Restart
+			continue
+		}
+
 		if err == nil {
 			failures = 0
-		} else if errors.Is(err, ErrUnsupportedAudioCodec) {
-			slog.Error("Failed to play unsupported entry", slog.String("title", entry.Title), slog.Any("error", err))
+			events.TrackFinished(entry)
+			if b.Repeat() {
+				b.mutex.Lock()
+				b.state.Queue.PushFront(entry)
+				b.mutex.Unlock()
+			}
+		} else if errors.Is(err, ytdlp.ErrRateLimited) {
+			slog.Error("Rate limited, stopping playback", slog.String("title", entry.Title), slog.Any("error", err))
+			b.state.Metrics.SongsFailed.WithLabelValues(failureReason(err)).Inc()
+			events.TrackFailed(entry, err)
+			// Every song streamed using yt-dlp is likely to fail until the rate
+			// limit is lifted, so keep the entry rather than skipping through the
+			// queue
+			b.mutex.Lock()
+			b.state.Queue.PushFront(entry)
+			b.mutex.Unlock()
+			return err
+		} else if isPermanentFailure(err) {
+			slog.Error("Failed to play unplayable entry", slog.String("title", entry.Title), slog.Any("error", err))
+			b.state.Metrics.SongsFailed.WithLabelValues(failureReason(err)).Inc()
+			events.TrackFailed(entry, err)
 			// Skip to next
-			// TODO: Communicate the failure - write in chat (currently no way to send
-			// messages from the bot) or get the URL immediately on queue and complain
-			// then?
 		} else {
 			slog.Error("Failed to play entry", slog.Any("error", err))
+			b.state.Metrics.SongsFailed.WithLabelValues(failureReason(err)).Inc()
+			events.TrackFailed(entry, err)
 			// Try next
 			failures++
 		}
 	}
 
+	// Stopping playback, such as using Stop, is not an error
+	if failures < 5 {
+		return nil
+	}
+
 	return fmt.Errorf("too many errors")
 }
 
-// playOnce plays the entry, sending windows of OPUS-encoded audio to the
-// provided channel.
-func (b *Bot) playOnce(entry state.PlaylistEntry, opus chan<- []byte) error {
-	slog.Debug("Playing", slog.String("uri", entry.URI), slog.String("title", entry.Title), slog.String("source", string(entry.Source)))
+// isPermanentFailure returns whether or not err means that the entry will
+// never play, such as if it's private, as opposed to intermittent errors.
+func isPermanentFailure(err error) bool {
+	permanent := []error{
+		ErrUnsupportedAudioCodec,
+		ErrUnsupportedSource,
+		source.ErrNotFound,
+		ytdlp.ErrUnavailable,
+		ytdlp.ErrPrivate,
+		ytdlp.ErrAgeRestricted,
+		ytdlp.ErrGeoBlocked,
+		ytdlp.ErrNoFormat,
+	}
+
+	for _, target := range permanent {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// failureReason returns the reason an entry failed to play, used as a label of
+// metrics.
+func failureReason(err error) string {
+	var ytdlpErr ytdlp.Error
+	switch {
+	case errors.Is(err, ErrUnsupportedAudioCodec):
+		return "unsupported_codec"
+	case errors.Is(err, ErrUnsupportedSource):
+		return "unsupported_source"
+	case errors.Is(err, source.ErrNotFound):
+		return "not_found"
+	case errors.As(err, &ytdlpErr):
+		return ytdlpErr.Reason()
+	default:
+		return "unknown"
+	}
+}
+
+// playOnce plays the entry from the specified offset, sending windows of
+// OPUS-encoded audio to the provided channel.
+func (b *Bot) playOnce(entry state.PlaylistEntry, opus chan<- []byte, offset time.Duration, events EventSink) error {
+	slog.Debug("Playing", slog.String("uri", entry.URI), slog.String("title", entry.Title), slog.String("source", string(entry.Source)), slog.Duration("offset", offset))
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	b.mutex.Lock()
 	b.currentEntry = &entry
+	b.streamTitle = ""
 	b.isStreaming = true
 	b.cancelStream = cancel
-	b.state.History.AddEntry(entry)
+	// Restarted entries have already been played
+	if offset == 0 {
+		b.state.History.AddEntry(entry)
+	}
 	b.mutex.Unlock()
+	b.position.Store(0)
 
-	reader, writer := io.Pipe()
-	webmReader := webm.NewReader(reader)
-	go func() {
-		for {
-			frame, err := webmReader.Read()
-			if err == io.EOF {
-				slog.Debug("Stream ended")
-				break
-			} else if err == io.ErrClosedPipe {
-				slog.Debug("Stream closed")
-				break
-			} else if err != nil {
-				slog.Error("Failed to read webm OPUS frame", slog.Any("error", err))
-				cancel()
-				break
-			}
-
-			opus <- frame.Payload
-		}
-	}()
-
-	b.state.Metrics.SongsPlayed.Inc()
+	if offset == 0 {
+		b.state.Metrics.SongsPlayed.Inc()
+	}
 	b.state.Metrics.ActiveStreams.Inc()
 
 	playbackStarted := time.Now()
 
-	// TODO: Catch specific errors, like unsupported codec / not found
-	// TODO: err is "signal: killed" on normal shut down (e.g. skip), should be
-	// ignored
-	err := ytdlp.Stream(ctx, entry.URI, writer)
+	err := b.stream(ctx, entry, opus, offset, events)
+	if ctx.Err() != nil || errors.Is(err, ytdlp.ErrCancelled) {
+		// The stream was stopped, such as when skipping. yt-dlp is killed in the
+		// process, which is not an error
+		err = nil
+	}
+
 	var ytdlpErr ytdlp.Error
 	if errors.As(err, &ytdlpErr) {
-		slog.Error("Failed to stream using yt-dlp", slog.String("stderr", ytdlpErr.Stderr))
-		return err
-	} else if err != nil {
-		return err
+		slog.Error("Failed to stream using yt-dlp", slog.String("reason", ytdlpErr.Reason()), slog.String("stderr", ytdlpErr.Stderr))
 	}
 
 	b.state.Metrics.DurationPlayed.Add(time.Since(playbackStarted).Seconds())
@@ -433,6 +795,7 @@ func (b *Bot) playOnce(entry state.PlaylistEntry, opus chan<- []byte) error {
 
 	b.mutex.Lock()
 	b.currentEntry = nil
+	b.streamTitle = ""
 	b.isStreaming = false
 	b.cancelStream = nil
 	b.mutex.Unlock()
@@ -441,6 +804,114 @@ func (b *Bot) playOnce(entry state.PlaylistEntry, opus chan<- []byte) error {
 	return err
 }
 
+// stream streams the entry from the specified offset, sending windows of
+// OPUS-encoded audio to the provided channel until the stream ends or ctx is
+// done.
+func (b *Bot) stream(ctx context.Context, entry state.PlaylistEntry, opus chan<- []byte, offset time.Duration, events EventSink) error {
+	provider, ok := b.sources.Provider(entry.Source)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedSource, entry.Source)
+	}
+
+	stream, err := provider.Stream(ctx, entry.URI, &source.StreamOptions{
+		Volume: b.settings().Volume,
+		OnTitle: func(title string) {
+			b.mutex.Lock()
+			changed := b.streamTitle != title
+			b.streamTitle = title
+			b.mutex.Unlock()
+
+			if changed {
+				slog.Debug("Stream title changed", slog.String("uri", entry.URI), slog.String("title", title))
+				events.StreamTitleChanged(entry, title)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		frame, err := stream.ReadFrame()
+		if err == io.EOF {
+			slog.Debug("Stream ended")
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Discord expects frames of 20ms
+		position := time.Duration(b.position.Add(int64(20 * time.Millisecond)))
+		// Skip frames until the offset is reached
+		if position <= offset {
+			continue
+		}
+
+		if !b.waitWhilePaused(ctx) {
+			return nil
+		}
+
+		select {
+		case opus <- frame:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// QueueEntries adds already resolved entries, such as imported ones, to the
+// end of the playlist. Returns the queued entries and the number of entries
+// rejected by checkEntries. Entries beyond the limit set in the options are
+// left out. Returns ErrQueueLimitReached if the entity has already reached the
+// limit.
+func (b *Bot) QueueEntries(entries []state.PlaylistEntry, addedBy state.Entity, options *QueueOptions) ([]state.PlaylistEntry, int, error) {
+	slog.Debug("Queueing entries", slog.Int("entries", len(entries)))
+	if options == nil {
+		options = &QueueOptions{}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	remaining := -1
+	if options.MaxPerUser > 0 {
+		remaining = options.MaxPerUser - b.queuedBy(addedBy)
+		if remaining <= 0 {
+			return nil, 0, ErrQueueLimitReached
+		}
+	}
+
+	checked := b.checkEntries(entries)
+	rejected := len(entries) - len(checked)
+
+	if remaining >= 0 && len(checked) > remaining {
+		checked = checked[:remaining]
+	}
+
+	for _, entry := range checked {
+		b.state.Queue.AddEntry(entry)
+	}
+	b.interruptLiveStream(checked)
+
+	return checked, rejected, nil
+}
+
+// SuggestEntries adds already resolved entries to the suggestions. Returns the
+// added entries, leaving out those rejected by checkEntries.
+func (b *Bot) SuggestEntries(entries []state.PlaylistEntry) []state.PlaylistEntry {
+	slog.Debug("Adding entries to suggestions", slog.Int("entries", len(entries)))
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	checked := b.checkEntries(entries)
+	for _, entry := range checked {
+		b.state.Suggestions.AddEntry(entry)
+	}
+
+	return checked
+}
+
 // ClearPlaylist clears all entries of the playlist.
 func (b *Bot) ClearPlaylist() {
 	slog.Debug("Clearing playlist")
@@ -472,10 +943,46 @@ func (b *Bot) Stop() {
 		b.cancelStream()
 	}
 
-	b.ExtrapolationType = ExtrapolationTypeNone
-	if b.state.Config.ExtrapolateWhenEmpty {
-		b.ExtrapolationType = ExtrapolationTypeHistory
+	if b.resumed != nil {
+		close(b.resumed)
+		b.resumed = nil
+	}
+
+	b.ExtrapolationType = defaultExtrapolationType(b.state.Config(), b.llmClient())
+}
+
+// Leave stops playback like Stop, but puts the current entry back at the front
+// of the queue so that it's played again once playback is restarted.
+func (b *Bot) Leave() {
+	b.mutex.Lock()
+	// Repeated entries are put back once they end
+	if b.isStreaming && b.currentEntry != nil && !b.repeat {
+		b.state.Queue.PushFront(*b.currentEntry)
 	}
+	b.mutex.Unlock()
+
+	b.Stop()
+}
+
+// Restart restarts the stream of the current entry at the current position.
+// Used to recover interrupted streams.
+func (b *Bot) Restart() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.isStreaming || b.currentEntry == nil || b.restarting {
+		return
+	}
+
+	slog.Debug("Restarting stream", slog.Duration("position", b.Position()))
+	b.restarting = true
+	b.restartAt = b.Position()
+	// Live streams can't be rewound, so pick up where they are now
+	if b.currentEntry.Source == state.SourceRadio {
+		b.restartAt = 0
+	}
+	b.state.Queue.PushFront(*b.currentEntry)
+	b.cancelStream()
 }
 
 // Skip stops the currently playing stream.
@@ -498,11 +1005,170 @@ func (b *Bot) SkipN(n int) {
 	}
 }
 
+// SkipEntry skips the entry, as returned by NowPlaying, if it's still
+// playing. Returns false if another entry has started playing since, so that
+// a decision made about one entry isn't applied to another.
+func (b *Bot) SkipEntry(entry *state.PlaylistEntry) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.isStreaming || b.currentEntry != entry {
+		return false
+	}
+
+	slog.Debug("Skipping playing stream", slog.String("title", entry.Title))
+	b.cancelStream()
+	return true
+}
+
 // NowPlaying returns the current playlist entry, or nil if nothing is playing.
+// Each playback of an entry has its own pointer.
 func (b *Bot) NowPlaying() *state.PlaylistEntry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
 	return b.currentEntry
 }
 
+// StreamTitle returns the title of the song played by the current entry, if
+// it's a live stream such as a radio station. Empty if unknown.
+func (b *Bot) StreamTitle() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.streamTitle
+}
+
+// Position returns the playback position of the current entry.
+func (b *Bot) Position() time.Duration {
+	return time.Duration(b.position.Load())
+}
+
+// Pause pauses playback until Resume is called.
+func (b *Bot) Pause() {
+	slog.Debug("Pausing playback")
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.resumed == nil {
+		b.resumed = make(chan struct{})
+	}
+}
+
+// Resume resumes paused playback.
+func (b *Bot) Resume() {
+	slog.Debug("Resuming playback")
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.resumed != nil {
+		close(b.resumed)
+		b.resumed = nil
+	}
+}
+
+// Paused returns whether or not playback is paused.
+func (b *Bot) Paused() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.resumed != nil
+}
+
+// waitWhilePaused blocks while playback is paused. Returns false if ctx is
+// done before playback is resumed.
+func (b *Bot) waitWhilePaused(ctx context.Context) bool {
+	b.mutex.Lock()
+	resumed := b.resumed
+	b.mutex.Unlock()
+
+	if resumed == nil {
+		return true
+	}
+
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SetRepeat sets whether or not to repeat the current entry once it ends.
+func (b *Bot) SetRepeat(repeat bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.repeat = repeat
+}
+
+// Repeat returns whether or not the current entry is repeated once it ends.
+func (b *Bot) Repeat() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.repeat
+}
+
+// Shuffle shuffles the queue.
+func (b *Bot) Shuffle() {
+	slog.Debug("Shuffling queue")
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state.Queue.Shuffle()
+}
+
+// Like adds the currently playing entry to the entity's liked songs.
+// Returns the liked entry, or nil if nothing is playing.
+func (b *Bot) Like(likedBy state.Entity) *state.PlaylistEntry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.currentEntry == nil {
+		return nil
+	}
+
+	entry := *b.currentEntry
+	entry.Time = time.Now()
+	entry.AddedBy = likedBy
+	b.state.Likes.AddEntry(entry)
+	return &entry
+}
+
+// settings returns the settings of the guild the bot is playing in. The guild
+// is set by Play, so the caller must either be Play or hold the mutex.
+func (b *Bot) settings() state.GuildSettings {
+	return b.state.Guilds.Settings(b.guildID)
+}
+
+// SetLLM replaces the LLM client used by the bot. Unless the way of
+// extrapolating has been changed since the bot was stopped, it's updated to
+// the default for the new client.
+func (b *Bot) SetLLM(llm llm.Client) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.llmMutex.Lock()
+	defer b.llmMutex.Unlock()
+
+	config := b.state.Config()
+	if b.ExtrapolationType == defaultExtrapolationType(config, b.llm) {
+		b.ExtrapolationType = defaultExtrapolationType(config, llm)
+	}
+
+	b.llm = llm
+}
+
+// llmClient returns the LLM client used by the bot, or nil if none is
+// configured.
+func (b *Bot) llmClient() llm.Client {
+	b.llmMutex.RLock()
+	defer b.llmMutex.RUnlock()
+
+	return b.llm
+}
+
 func (b *Bot) LLMEnabled() bool {
-	return b.llm != nil
+	return b.llmClient() != nil
 }
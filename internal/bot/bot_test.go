@@ -0,0 +1,304 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/llm"
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureReason(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Err       error
+		Reason    string
+		Permanent bool
+	}{
+		{
+			Name:      "private",
+			Err:       fmt.Errorf("stream: %w", ytdlp.Error{ExitCode: 1, Err: ytdlp.ErrPrivate}),
+			Reason:    "private",
+			Permanent: true,
+		},
+		{
+			Name:      "rate limited",
+			Err:       ytdlp.Error{ExitCode: 1, Err: ytdlp.ErrRateLimited},
+			Reason:    "rate_limited",
+			Permanent: false,
+		},
+		{
+			Name:      "unknown yt-dlp error",
+			Err:       ytdlp.Error{ExitCode: 1},
+			Reason:    "unknown",
+			Permanent: false,
+		},
+		{
+			Name:      "unsupported source",
+			Err:       fmt.Errorf("%w: %s", ErrUnsupportedSource, "other"),
+			Reason:    "unsupported_source",
+			Permanent: true,
+		},
+		{
+			Name:      "not found",
+			Err:       source.ErrNotFound,
+			Reason:    "not_found",
+			Permanent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Reason, failureReason(testCase.Err))
+			assert.Equal(t, testCase.Permanent, isPermanentFailure(testCase.Err))
+		})
+	}
+}
+
+func TestSkipEntry(t *testing.T) {
+	current := &state.PlaylistEntry{Title: "Current"}
+	cancelled := false
+
+	b := &Bot{
+		currentEntry: current,
+		isStreaming:  true,
+		cancelStream: func() { cancelled = true },
+	}
+
+	// Decisions about a previous entry must not skip the current one
+	assert.False(t, b.SkipEntry(&state.PlaylistEntry{Title: "Current"}))
+	assert.False(t, cancelled)
+
+	assert.True(t, b.SkipEntry(current))
+	assert.True(t, cancelled)
+}
+
+func TestQueueEntriesLimit(t *testing.T) {
+	user := state.Entity{Role: state.RoleUser, ID: "user"}
+	other := state.Entity{Role: state.RoleUser, ID: "other"}
+
+	b := &Bot{state: &state.State{Queue: state.NewPlaylist()}}
+	b.state.SetConfig(&state.Config{})
+	b.state.Queue.AddEntry(state.PlaylistEntry{Title: "Queued", AddedBy: user})
+	b.state.Queue.AddEntry(state.PlaylistEntry{Title: "Other", AddedBy: other})
+
+	entries := make([]state.PlaylistEntry, 5)
+	for i := range entries {
+		entries[i] = state.PlaylistEntry{Title: fmt.Sprintf("Imported %d", i), AddedBy: user}
+	}
+
+	options := &QueueOptions{MaxPerUser: 3}
+
+	queued, rejected, err := b.QueueEntries(entries, user, options)
+	require.NoError(t, err)
+	assert.Equal(t, entries[:2], queued)
+	assert.Equal(t, 0, rejected)
+	assert.Equal(t, 4, b.state.Queue.Len())
+
+	_, _, err = b.QueueEntries(entries, user, options)
+	assert.ErrorIs(t, err, ErrQueueLimitReached)
+	assert.Equal(t, 4, b.state.Queue.Len())
+
+	// Without a limit, everything is queued
+	queued, _, err = b.QueueEntries(entries, user, nil)
+	require.NoError(t, err)
+	assert.Equal(t, entries, queued)
+	assert.Equal(t, 9, b.state.Queue.Len())
+}
+
+func TestImportedEntriesAreChecked(t *testing.T) {
+	user := state.Entity{Role: state.RoleUser, ID: "user"}
+
+	b := &Bot{state: &state.State{Queue: state.NewPlaylist(), Suggestions: state.NewPlaylist()}}
+	b.state.SetConfig(&state.Config{MaxDuration: 10 * time.Minute})
+
+	short := state.PlaylistEntry{Title: "Short", Source: state.SourceYouTube, URI: "short", Duration: 3 * time.Minute, AddedBy: user}
+	unknown := state.PlaylistEntry{Title: "Unknown", Source: state.SourceYouTube, URI: "unknown", AddedBy: user}
+	long := state.PlaylistEntry{Title: "Long", Source: state.SourceYouTube, URI: "long", Duration: time.Hour, AddedBy: user}
+	radio := state.PlaylistEntry{Title: "Radio", Source: state.SourceRadio, URI: "https://example.com/stream", Duration: time.Hour, AddedBy: user}
+	entries := []state.PlaylistEntry{short, long, unknown, radio}
+
+	queued, rejected, err := b.QueueEntries(entries, user, &QueueOptions{MaxPerUser: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []state.PlaylistEntry{short, unknown}, queued)
+	assert.Equal(t, 1, rejected)
+
+	suggested := b.SuggestEntries(entries)
+	assert.Equal(t, []state.PlaylistEntry{short, unknown, radio}, suggested)
+	assert.Equal(t, suggested, b.state.Suggestions.Entries())
+}
+
+var _ llm.Client = (*stubLLM)(nil)
+
+// stubLLM is an LLM client which never answers.
+type stubLLM struct{}
+
+func (stubLLM) Chat(ctx context.Context, request *llm.ChatRequest) (*llm.ChatResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+// promptRecorder is an LLM client which records the system prompt and answers
+// with no results.
+type promptRecorder struct {
+	prompt string
+}
+
+func (r *promptRecorder) Chat(ctx context.Context, request *llm.ChatRequest) (*llm.ChatResponse, error) {
+	r.prompt = request.Messages[0].Content
+	return &llm.ChatResponse{Message: llm.Message{Role: llm.RoleAssistant, Content: "no results"}}, nil
+}
+
+func TestSearchUsesGuildLookback(t *testing.T) {
+	recorder := &promptRecorder{}
+	b := &Bot{
+		state: &state.State{
+			History: state.NewPlaylist(),
+			Guilds:  state.NewGuilds(),
+		},
+		llm:     recorder,
+		sources: source.NewRegistry(),
+	}
+	b.state.SetConfig(&state.Config{ExtrapolationLookback: 3})
+	for i := range 3 {
+		b.state.History.AddEntry(state.PlaylistEntry{Title: fmt.Sprintf("Song %d", i)})
+	}
+	b.state.Guilds.SetSettings("guild", state.GuildSettings{ExtrapolationLookback: 1})
+
+	// The bot isn't playing in any guild, so the guild must be passed in
+	_, err := b.Search(context.Background(), "more", &SearchOptions{UseAI: true, GuildID: "guild"})
+	require.NoError(t, err)
+	assert.Contains(t, recorder.prompt, "Song 2")
+	assert.NotContains(t, recorder.prompt, "Song 1")
+
+	_, err = b.Search(context.Background(), "more", &SearchOptions{UseAI: true, GuildID: "other"})
+	require.NoError(t, err)
+	assert.Contains(t, recorder.prompt, "Song 0")
+}
+
+func TestSetLLM(t *testing.T) {
+	b := &Bot{state: &state.State{}}
+	b.state.SetConfig(&state.Config{ExtrapolateWhenEmpty: true})
+	b.ExtrapolationType = defaultExtrapolationType(b.state.Config(), nil)
+	require.Equal(t, ExtrapolationTypeRecommended, b.ExtrapolationType)
+
+	// Enabling an LLM switches to the LLM-based default
+	b.SetLLM(stubLLM{})
+	assert.Equal(t, ExtrapolationTypeHistory, b.ExtrapolationType)
+
+	// Disabling it switches back
+	b.SetLLM(nil)
+	assert.Equal(t, ExtrapolationTypeRecommended, b.ExtrapolationType)
+
+	// A way of extrapolating chosen by a user is kept
+	b.ExtrapolationType = ExtrapolationTypeSuggest
+	b.SetLLM(stubLLM{})
+	assert.Equal(t, ExtrapolationTypeSuggest, b.ExtrapolationType)
+}
+
+var _ source.Provider = (*blockingProvider)(nil)
+
+// blockingProvider streams tracks which never end. Once stopped, the streams
+// fail like a killed yt-dlp process.
+type blockingProvider struct{}
+
+func (blockingProvider) Source() state.Source {
+	return state.SourceYouTube
+}
+
+func (blockingProvider) Search(ctx context.Context, query string, n int) ([]source.Track, error) {
+	return nil, nil
+}
+
+func (blockingProvider) Resolve(ctx context.Context, uri string) ([]source.Track, bool, error) {
+	return nil, false, nil
+}
+
+func (blockingProvider) Stream(ctx context.Context, uri string, options *source.StreamOptions) (source.Stream, error) {
+	return blockingStream{ctx: ctx}, nil
+}
+
+func (blockingProvider) Metadata(ctx context.Context, uri string) (source.Track, error) {
+	return source.Track{}, source.ErrNotFound
+}
+
+type blockingStream struct {
+	ctx context.Context
+}
+
+func (s blockingStream) ReadFrame() ([]byte, error) {
+	<-s.ctx.Done()
+	return nil, errors.New("signal: killed")
+}
+
+func (s blockingStream) Close() error {
+	return nil
+}
+
+// recordingEventSink records the titles of started, finished and failed
+// entries.
+type recordingEventSink struct {
+	nopEventSink
+	mutex    sync.Mutex
+	started  chan string
+	finished []string
+	failed   []string
+}
+
+func (s *recordingEventSink) TrackStarted(entry state.PlaylistEntry) {
+	s.started <- entry.Title
+}
+
+func (s *recordingEventSink) TrackFinished(entry state.PlaylistEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.finished = append(s.finished, entry.Title)
+}
+
+func (s *recordingEventSink) TrackFailed(entry state.PlaylistEntry, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failed = append(s.failed, entry.Title)
+}
+
+func TestPlaySkipIsNotAFailure(t *testing.T) {
+	config := state.DefaultConfig()
+	config.ExtrapolateWhenEmpty = false
+
+	s := &state.State{
+		Queue:   state.NewPlaylist(),
+		History: state.NewPlaylist(),
+		Guilds:  state.NewGuilds(),
+		Metrics: state.NewMetrics(),
+	}
+	s.SetConfig(config)
+	s.Queue.Push(state.PlaylistEntry{Title: "First", Source: state.SourceYouTube, URI: "1"})
+	s.Queue.Push(state.PlaylistEntry{Title: "Second", Source: state.SourceYouTube, URI: "2"})
+
+	b := New(s, nil, source.NewRegistry(blockingProvider{}))
+	events := &recordingEventSink{started: make(chan string, 2)}
+
+	played := make(chan error, 1)
+	go func() {
+		played <- b.Play("1", make(chan []byte), events)
+	}()
+
+	// Skipping kills the stream, which is not an error
+	assert.Equal(t, "First", <-events.started)
+	b.Skip()
+
+	// Neither is stopping, such as when leaving an idle channel
+	assert.Equal(t, "Second", <-events.started)
+	b.Stop()
+
+	require.NoError(t, <-played)
+	assert.Equal(t, []string{"First", "Second"}, events.finished)
+	assert.Empty(t, events.failed)
+}
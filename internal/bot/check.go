@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
+)
+
+const (
+	// inspectionTimeout is the time to wait for yt-dlp to resolve the metadata
+	// of a song being queued.
+	inspectionTimeout = 5 * time.Second
+	// restrictedAgeLimit is the age limit of content that requires signing in,
+	// which the bot can't do.
+	restrictedAgeLimit = 18
+)
+
+var (
+	ErrLivestream    = errors.New("livestreams can't be queued")
+	ErrAgeRestricted = errors.New("age restricted")
+	ErrTooLong       = errors.New("too long")
+	ErrUnavailable   = errors.New("unavailable")
+)
+
+// checkTrack returns an error if the track can't be queued, based on what's
+// already known about it. Radio stations are live by design and always pass.
+func (b *Bot) checkTrack(track source.Track) error {
+	if track.Source == state.SourceRadio {
+		return nil
+	}
+
+	if track.Live {
+		return ErrLivestream
+	}
+
+	if maxDuration := b.state.Config().MaxDuration; maxDuration > 0 && track.Duration > maxDuration {
+		return ErrTooLong
+	}
+
+	return nil
+}
+
+// checkEntries returns the entries which pass checkTrack. Already resolved
+// entries, such as imported ones, are not inspected as there may be thousands
+// of them. Livestreams are therefore not detected, but entries known to be too
+// long are rejected.
+func (b *Bot) checkEntries(entries []state.PlaylistEntry) []state.PlaylistEntry {
+	checked := make([]state.PlaylistEntry, 0, len(entries))
+	for _, entry := range entries {
+		err := b.checkTrack(source.Track{
+			Source:   entry.Source,
+			URI:      entry.URI,
+			Duration: entry.Duration,
+		})
+		if err != nil {
+			slog.Debug("Rejected entry", slog.String("uri", entry.URI), slog.Any("error", err))
+			continue
+		}
+
+		checked = append(checked, entry)
+	}
+	return checked
+}
+
+// inspectTrack is like checkTrack, but resolves the metadata of tracks
+// streamed using yt-dlp in order to reject livestreams, age restricted and
+// unavailable content before it fails mid-playback. Failing to run yt-dlp,
+// such as if it times out or is rate limited, lets the track through.
+func (b *Bot) inspectTrack(ctx context.Context, track source.Track) error {
+	if err := b.checkTrack(track); err != nil {
+		return err
+	}
+
+	if track.Source == state.SourceLocal || track.Source == state.SourceRadio {
+		return nil
+	}
+
+	location, err := state.EntryURL(track.Entry(state.Entity{}))
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, inspectionTimeout)
+	defer cancel()
+
+	slog.Debug("Inspecting track", slog.String("url", location))
+	info, err := ytdlp.Metadata(ctx, location)
+	var ytdlpErr ytdlp.Error
+	if ctx.Err() != nil {
+		slog.Warn("Timed out inspecting track, queueing it anyway", slog.String("url", location))
+		return nil
+	} else if errors.Is(err, ytdlp.ErrRateLimited) {
+		// Not the track's fault, it may well be playable later on
+		slog.Warn("Rate limited inspecting track, queueing it anyway", slog.String("url", location))
+		return nil
+	} else if errors.Is(err, ytdlp.ErrAgeRestricted) {
+		return ErrAgeRestricted
+	} else if errors.As(err, &ytdlpErr) {
+		slog.Debug("Track is unavailable", slog.String("url", location), slog.String("reason", ytdlpErr.Reason()), slog.String("stderr", ytdlpErr.Stderr))
+		// Keep the cause, such as ytdlp.ErrPrivate, to tell users why
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	} else if err != nil {
+		slog.Warn("Failed to inspect track, queueing it anyway", slog.String("url", location), slog.Any("error", err))
+		return nil
+	}
+
+	if info.AgeLimit >= restrictedAgeLimit {
+		return ErrAgeRestricted
+	}
+
+	return b.checkTrack(source.Track{
+		Source:   track.Source,
+		Live:     info.Live,
+		Duration: info.Duration,
+	})
+}
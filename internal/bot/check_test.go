@@ -0,0 +1,27 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTrack(t *testing.T) {
+	config := state.DefaultConfig()
+	config.MaxDuration = 10 * time.Minute
+	b := &Bot{state: &state.State{}}
+	b.state.SetConfig(config)
+
+	assert.NoError(t, b.checkTrack(source.Track{Source: state.SourceYouTube, Duration: 3 * time.Minute}))
+	assert.ErrorIs(t, b.checkTrack(source.Track{Source: state.SourceYouTube, Live: true}), ErrLivestream)
+	assert.ErrorIs(t, b.checkTrack(source.Track{Source: state.SourceYouTube, Duration: time.Hour}), ErrTooLong)
+
+	// Radio stations are always live
+	assert.NoError(t, b.checkTrack(source.Track{Source: state.SourceRadio, Live: true}))
+
+	config.MaxDuration = 0
+	assert.NoError(t, b.checkTrack(source.Track{Source: state.SourceLocal, Duration: time.Hour}))
+}
@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"github.com/AlexGustafsson/clabbe/internal/state"
+)
+
+// EventSink receives events about playback, such as to announce them to
+// listeners.
+type EventSink interface {
+	// TrackStarted is called when an entry starts playing.
+	TrackStarted(entry state.PlaylistEntry)
+	// TrackFinished is called when an entry has been played, or skipped.
+	TrackFinished(entry state.PlaylistEntry)
+	// TrackFailed is called when an entry could not be played.
+	TrackFailed(entry state.PlaylistEntry, err error)
+	// QueueEmpty is called when the queue is empty and playback stops.
+	QueueEmpty()
+	// ExtrapolationAdded is called when the bot adds entries to the queue on
+	// its own.
+	ExtrapolationAdded(entries []state.PlaylistEntry)
+	// StreamTitleChanged is called when the title of a live stream changes,
+	// such as when a radio station starts playing another song. The title may
+	// be empty.
+	StreamTitleChanged(entry state.PlaylistEntry, title string)
+}
+
+// nopEventSink is an EventSink that ignores all events.
+type nopEventSink struct{}
+
+func (nopEventSink) TrackStarted(state.PlaylistEntry)               {}
+func (nopEventSink) TrackFinished(state.PlaylistEntry)              {}
+func (nopEventSink) TrackFailed(state.PlaylistEntry, error)         {}
+func (nopEventSink) QueueEmpty()                                    {}
+func (nopEventSink) ExtrapolationAdded([]state.PlaylistEntry)       {}
+func (nopEventSink) StreamTitleChanged(state.PlaylistEntry, string) {}
@@ -2,17 +2,21 @@ package bot
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/AlexGustafsson/clabbe/internal/prompt"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestRenderPrompt(t *testing.T) {
-	output, err := RenderPrompt(defaultThemeSuggestionTemplate, nil)
+	output, err := prompt.Render(defaultThemeSuggestionTemplate, nil)
 	require.NoError(t, err)
 	fmt.Println(output)
 
-	output, err = RenderPrompt(defaultSongSuggestionTemplate, map[string]any{
+	output, err = prompt.Render(defaultSongSuggestionTemplate, map[string]any{
 		"history": []map[string]any{
 			{"name": "Foo"},
 			{"name": "Bar"},
@@ -23,6 +27,8 @@ func TestRenderPrompt(t *testing.T) {
 			{"name": "Foo"},
 			{"name": "Bar"},
 		},
+		"requester": "Foo",
+		"guild":     "Bar",
 		"similar": []map[string]any{
 			{"name": "Foo"},
 			{"name": "Bar"},
@@ -37,3 +43,65 @@ func TestRenderPrompt(t *testing.T) {
 	require.NoError(t, err)
 	fmt.Println(output)
 }
+
+func TestRenderPromptShortHistory(t *testing.T) {
+	for n := range 5 {
+		t.Run(fmt.Sprintf("%d entries", n), func(t *testing.T) {
+			history := make([]map[string]any, n)
+			similar := make([]map[string]any, n)
+			for i := range n {
+				history[i] = map[string]any{"name": fmt.Sprintf("Song %d", i)}
+				similar[i] = map[string]any{"name": fmt.Sprintf("Similar %d", i)}
+			}
+
+			output, err := prompt.Render(defaultSongSuggestionTemplate, map[string]any{
+				"history": history,
+				"similar": similar,
+			})
+			require.NoError(t, err)
+
+			for i := range n {
+				assert.Contains(t, output, fmt.Sprintf("%d. Song %d", i+1, i))
+				assert.Contains(t, output, fmt.Sprintf("- Similar %d", i))
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	content := `1. Foo - Bar
+2) Bar - Baz
+
+- Baz - Qux
+Qux - Quux`
+
+	assert.Equal(t, []string{"Foo - Bar", "Bar - Baz", "Baz - Qux", "Qux - Quux"}, parseList(content))
+}
+
+func TestSongSuggestionPromptSimilar(t *testing.T) {
+	b := &Bot{state: &state.State{}}
+	b.state.SetConfig(&state.Config{})
+
+	history := make([]state.PlaylistEntry, 5)
+	for i := range history {
+		history[i] = state.PlaylistEntry{Title: fmt.Sprintf("Song %d", i)}
+	}
+
+	for n := range 5 {
+		t.Run(fmt.Sprintf("%d similar songs", n), func(t *testing.T) {
+			similar := make([]string, n)
+			for i := range n {
+				similar[i] = fmt.Sprintf("Similar %d", i)
+			}
+
+			output, err := b.songSuggestionPrompt(PromptData{History: history, Similar: similar})
+			require.NoError(t, err)
+
+			assert.Contains(t, output, "5. Song 4")
+			assert.Equal(t, n, strings.Count(output, "- Similar "))
+			for i := range n {
+				assert.Contains(t, output, fmt.Sprintf("- Similar %d", i))
+			}
+		})
+	}
+}
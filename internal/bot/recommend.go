@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/youtube"
+)
+
+const (
+	// recommendationSeeds is the number of recently played songs to base
+	// recommendations on.
+	recommendationSeeds = 3
+	// recommendationsPerExtrapolation is the number of recommended songs to
+	// queue each time the queue runs dry.
+	recommendationsPerExtrapolation = 5
+	// similarSongsInPrompt is the number of similar songs to include in
+	// prompts.
+	similarSongsInPrompt = 10
+	// minRecommendedDuration is the shortest song to recommend.
+	minRecommendedDuration = time.Minute
+	// maxRecommendedDuration is the longest song to recommend.
+	maxRecommendedDuration = 10 * time.Minute
+	// minRecommendedViews is the least number of views of a recommended song.
+	minRecommendedViews = 10_000
+)
+
+// extrapolateWithRecommendations queues videos YouTube recommends for the
+// most recently played songs. Requires no LLM.
+func (b *Bot) extrapolateWithRecommendations(ctx context.Context) ([]state.PlaylistEntry, error) {
+	b.mutex.Lock()
+	history := b.state.History.Entries()
+	queue := b.state.Queue.Entries()
+	b.mutex.Unlock()
+
+	slices.Reverse(history)
+
+	// Avoid repeating songs already played or queued
+	seen := make(map[string]bool)
+	for _, entry := range append(history, queue...) {
+		if entry.Source == state.SourceYouTube {
+			seen[entry.URI] = true
+		}
+	}
+
+	slog.Debug("Extrapolating songs based on recommendations")
+	picked, err := recommend(ctx, history, seen, recommendationsPerExtrapolation)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := state.Entity{
+		Role: state.RoleSystem,
+	}
+	added := make([]state.PlaylistEntry, len(picked))
+	b.mutex.Lock()
+	for i, result := range picked {
+		added[i] = source.Track{
+			Source:   state.SourceYouTube,
+			URI:      result.ID,
+			Title:    result.Title,
+			Duration: result.Duration,
+			Views:    result.Views,
+		}.Entry(entity)
+		b.state.Queue.AddEntry(added[i])
+	}
+	b.mutex.Unlock()
+
+	return added, nil
+}
+
+// similarSongs returns the titles of songs YouTube recommends for the most
+// recent songs of history, which is ordered most recent first. Used to let
+// the LLM know of songs it might not have memorized.
+func similarSongs(ctx context.Context, history []state.PlaylistEntry) []string {
+	seen := make(map[string]bool)
+	for _, entry := range history {
+		if entry.Source == state.SourceYouTube {
+			seen[entry.URI] = true
+		}
+	}
+
+	// The prompt works without similar songs, so don't fail
+	picked, err := recommend(ctx, history, seen, similarSongsInPrompt)
+	if err != nil {
+		slog.Warn("Failed to get similar songs", slog.Any("error", err))
+		return nil
+	}
+
+	similar := make([]string, len(picked))
+	for i, result := range picked {
+		similar[i] = result.Title
+	}
+	return similar
+}
+
+// recommend returns at most n videos YouTube recommends for the most recent
+// songs of history, which is ordered most recent first.
+func recommend(ctx context.Context, history []state.PlaylistEntry, seen map[string]bool, n int) ([]youtube.RecommendResult, error) {
+	seeds := make([]string, 0)
+	for _, entry := range history {
+		if len(seeds) == recommendationSeeds {
+			break
+		}
+
+		if entry.Source == state.SourceYouTube {
+			seeds = append(seeds, entry.URI)
+		}
+	}
+
+	if len(seeds) == 0 {
+		slog.Debug("No history to base recommendations on")
+		return nil, nil
+	}
+
+	slog.Debug("Fetching recommendations", slog.Any("seeds", seeds))
+	client := youtube.NewRecommendClient()
+	recommendations := make([][]youtube.RecommendResult, 0)
+	for _, seed := range seeds {
+		results, err := client.Recommend(ctx, seed)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, results)
+	}
+
+	return pickRecommendations(recommendations, seen, n), nil
+}
+
+// pickRecommendations picks at most n recommendations, taking turns between
+// the recommendations of each seed so that all of them are represented.
+// Results already seen and results unlikely to be songs are skipped.
+func pickRecommendations(recommendations [][]youtube.RecommendResult, seen map[string]bool, n int) []youtube.RecommendResult {
+	picked := make([]youtube.RecommendResult, 0)
+	next := make([]int, len(recommendations))
+	for len(picked) < n {
+		found := false
+		for i, results := range recommendations {
+			for next[i] < len(results) {
+				result := results[next[i]]
+				next[i]++
+
+				if seen[result.ID] || result.Duration < minRecommendedDuration || result.Duration > maxRecommendedDuration || result.Views < minRecommendedViews {
+					continue
+				}
+
+				seen[result.ID] = true
+				picked = append(picked, result)
+				found = true
+				break
+			}
+
+			if len(picked) == n {
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	return picked
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/youtube"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickRecommendations(t *testing.T) {
+	song := func(id string) youtube.RecommendResult {
+		return youtube.RecommendResult{ID: id, Duration: 3 * time.Minute, Views: 1_000_000}
+	}
+
+	recommendations := [][]youtube.RecommendResult{
+		{
+			song("played"),
+			song("a1"),
+			{ID: "short", Duration: 30 * time.Second, Views: 1_000_000},
+			{ID: "mix", Duration: 2 * time.Hour, Views: 1_000_000},
+			song("a2"),
+			song("a3"),
+		},
+		{
+			song("a1"),
+			{ID: "unpopular", Duration: 3 * time.Minute, Views: 10},
+			song("b1"),
+		},
+	}
+
+	seen := map[string]bool{"played": true}
+
+	picked := pickRecommendations(recommendations, seen, 3)
+	ids := make([]string, len(picked))
+	for i, result := range picked {
+		ids[i] = result.ID
+	}
+	assert.Equal(t, []string{"a1", "b1", "a2"}, ids)
+
+	picked = pickRecommendations(recommendations, map[string]bool{}, 10)
+	assert.Len(t, picked, 5)
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+)
+
+const (
+	// maxSearchResultDuration is the duration at which search results are
+	// considered to be compilations rather than songs.
+	maxSearchResultDuration = time.Hour
+	// searchCandidates is the number of top search results to pick the best
+	// scored result from. Results further down are rarely what was searched for.
+	searchCandidates = 5
+)
+
+// ScoreFunc scores how well a search result matches a query. Higher is better.
+type ScoreFunc func(query string, result source.Track) int
+
+// unwantedVersions are words in titles identifying versions of songs that
+// are unlikely to be wanted, unless searched for.
+var unwantedVersions = []string{
+	"live",
+	"cover",
+	"karaoke",
+	"instrumental",
+	"reaction",
+	"nightcore",
+	"slowed",
+	"sped up",
+	"8d",
+}
+
+// DefaultScore scores results using the default weights. See ScoreWith.
+func DefaultScore(query string, result source.Track) int {
+	return ScoreWith(state.DefaultConfig().Scoring)(query, result)
+}
+
+// ScoreWith returns a ScoreFunc which prefers songs uploaded by YouTube's
+// auto-generated "Topic" channels and official artist channels, and avoids
+// live versions, covers and the like unless searched for, as weighted by
+// config. Words are matched as a whole, so that "Alive" is not a live version.
+func ScoreWith(config state.ScoringConfig) ScoreFunc {
+	return func(query string, result source.Track) int {
+		score := 0
+
+		if strings.HasSuffix(result.Artist, " - Topic") {
+			score += config.Topic
+		}
+
+		if result.Official {
+			score += config.Official
+		} else if result.Verified {
+			score += config.Verified
+		}
+
+		title := words(result.Title)
+		query = words(query)
+		if strings.Contains(title, " official ") {
+			score += config.OfficialTitle
+		}
+
+		for _, version := range unwantedVersions {
+			version = " " + version + " "
+			if strings.Contains(title, version) && !strings.Contains(query, version) {
+				score -= config.UnwantedVersion
+			}
+		}
+
+		return score
+	}
+}
+
+// words returns the lower cased words of s separated and surrounded by
+// spaces, so that whole words can be matched using strings.Contains.
+func words(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// filterSearchResults removes livestreams and hour-long compilations from
+// search results.
+func filterSearchResults(results []source.Track) []source.Track {
+	return slices.DeleteFunc(slices.Clone(results), func(result source.Track) bool {
+		return result.Live || result.Duration >= maxSearchResultDuration
+	})
+}
+
+// bestSearchResult returns the best scored of the top search results, after
+// filtering. Returns false if there are no results left.
+func bestSearchResult(query string, results []source.Track, score ScoreFunc) (source.Track, bool) {
+	results = filterSearchResults(results)
+	if len(results) == 0 {
+		return source.Track{}, false
+	}
+
+	if score == nil {
+		return results[0], true
+	}
+
+	candidates := results[:min(len(results), searchCandidates)]
+	best := 0
+	bestScore := score(query, candidates[0])
+	for i, candidate := range candidates[1:] {
+		if candidateScore := score(query, candidate); candidateScore > bestScore {
+			best = i + 1
+			bestScore = candidateScore
+		}
+	}
+
+	return candidates[best], true
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultScore(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Query    string
+		Result   source.Track
+		Expected int
+	}{
+		{
+			Name:     "plain",
+			Query:    "never gonna give you up",
+			Result:   source.Track{Title: "Never Gonna Give You Up", Artist: "someone"},
+			Expected: 0,
+		},
+		{
+			Name:     "topic",
+			Query:    "never gonna give you up",
+			Result:   source.Track{Title: "Never Gonna Give You Up", Artist: "Rick Astley - Topic"},
+			Expected: 3,
+		},
+		{
+			Name:     "official artist",
+			Query:    "never gonna give you up",
+			Result:   source.Track{Title: "Never Gonna Give You Up (Official Music Video)", Artist: "Rick Astley", Official: true, Verified: true},
+			Expected: 4,
+		},
+		{
+			Name:     "unwanted cover",
+			Query:    "never gonna give you up",
+			Result:   source.Track{Title: "Never Gonna Give You Up (Cover)"},
+			Expected: -2,
+		},
+		{
+			Name:     "wanted live version",
+			Query:    "never gonna give you up live",
+			Result:   source.Track{Title: "Never Gonna Give You Up (Live)"},
+			Expected: 0,
+		},
+		{
+			Name:     "words containing unwanted versions",
+			Query:    "staying alive",
+			Result:   source.Track{Title: "Bee Gees - Stayin' Alive / Deliver Me (Oliver remix)"},
+			Expected: 0,
+		},
+		{
+			Name:     "unwanted multi-word version",
+			Query:    "blinding lights",
+			Result:   source.Track{Title: "Blinding Lights - Sped Up"},
+			Expected: -2,
+		},
+		{
+			Name:     "unwanted 8d version",
+			Query:    "blinding lights",
+			Result:   source.Track{Title: "Blinding Lights (8D Audio)"},
+			Expected: -2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, DefaultScore(testCase.Query, testCase.Result))
+		})
+	}
+}
+
+func TestScoreWith(t *testing.T) {
+	score := ScoreWith(state.ScoringConfig{Topic: 10, UnwantedVersion: 1})
+	assert.Equal(t, 10, score("song", source.Track{Title: "Song", Artist: "Artist - Topic", Official: true}))
+	assert.Equal(t, -1, score("song", source.Track{Title: "Song (Karaoke)"}))
+
+	// Without weights, every result scores the same
+	score = ScoreWith(state.ScoringConfig{})
+	assert.Equal(t, 0, score("song", source.Track{Title: "Song (Official Live)", Artist: "Artist - Topic", Official: true}))
+}
+
+func TestBestSearchResult(t *testing.T) {
+	results := []source.Track{
+		{URI: "live", Live: true},
+		{URI: "compilation", Duration: 2 * time.Hour},
+		{URI: "upload", Title: "Song", Duration: 3 * time.Minute},
+		{URI: "topic", Title: "Song", Artist: "Artist - Topic", Duration: 3 * time.Minute},
+	}
+
+	result, ok := bestSearchResult("song", results, DefaultScore)
+	assert.True(t, ok)
+	assert.Equal(t, "topic", result.URI)
+
+	result, ok = bestSearchResult("song", results, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "upload", result.URI)
+
+	_, ok = bestSearchResult("song", results[:2], DefaultScore)
+	assert.False(t, ok)
+}
@@ -1,14 +1,53 @@
 package discord
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AlexGustafsson/clabbe/internal/bot"
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/timeutil"
 	"github.com/AlexGustafsson/clabbe/internal/youtube"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
 )
 
+// maxPlaylistImportSize is the maximum size of an imported playlist file.
+const maxPlaylistImportSize = 1 << 20
+
+// maxMessageVideos is the maximum number of videos to queue from a message.
+const maxMessageVideos = 10
+
+// rejectedReply returns the reply to a song being rejected when queued, if
+// err is such a rejection.
+func rejectedReply(conn *Conn, err error) (string, bool) {
+	switch {
+	case errors.Is(err, bot.ErrLivestream):
+		return "Livestreams can't be queued", true
+	case errors.Is(err, bot.ErrAgeRestricted):
+		return "That song is age restricted, so I can't play it", true
+	case errors.Is(err, bot.ErrTooLong):
+		return fmt.Sprintf("Songs can be at most %s long", timeutil.FormatDuration(conn.State().Config().MaxDuration)), true
+	case errors.Is(err, ytdlp.ErrRateLimited):
+		return "I'm being rate limited, try again later", true
+	case errors.Is(err, ytdlp.ErrPrivate):
+		return "That song is private, so I can't play it", true
+	case errors.Is(err, ytdlp.ErrGeoBlocked):
+		return "That song isn't available in my region", true
+	case errors.Is(err, bot.ErrUnavailable):
+		return "That song isn't available", true
+	default:
+		return "", false
+	}
+}
+
 func PlayAction(ctx *Context, conn *Conn) (string, error) {
 	guildID, voiceChannelID, err := ctx.VoiceChannel()
 	if err == ErrNotInVoiceChannel {
@@ -17,17 +56,30 @@ func PlayAction(ctx *Context, conn *Conn) (string, error) {
 		return "", err
 	}
 
+	// DJs may move the bot to their channel
+	if current, ok := conn.VoiceChannelID(); ok && current != voiceChannelID {
+		if !conn.isDJ(guildID, ctx.event.Member) {
+			return fmt.Sprintf("I'm already playing in <#%s>", current), nil
+		}
+
+		if err := conn.Move(voiceChannelID); err != nil {
+			return "", err
+		}
+	}
+
 	auto, ok := ctx.Boolean("auto")
 	if ok && auto {
 		b := conn.Bot()
 		b.ExtrapolationType = bot.ExtrapolationTypeSuggest
 	}
 
-	conn.Play(guildID, voiceChannelID)
+	conn.Play(guildID, voiceChannelID, ctx.ChannelID())
 
 	currentEntry := conn.Bot().NowPlaying()
 	if currentEntry != nil {
-		return fmt.Sprintf("Currently playing **%s**", currentEntry.Title), nil
+		ctx.AddEmbed(nowPlayingEmbed(*currentEntry, conn.Bot()))
+		ctx.SetComponents(playerComponents(conn.Bot()))
+		return "", nil
 	}
 
 	return "On my way!", nil
@@ -46,9 +98,32 @@ func QueueAction(ctx *Context, conn *Conn) (string, error) {
 		return "Missing required query parameter", nil
 	}
 
-	entries, err := conn.Bot().Queue(ctx, query, ctx.Entity(), nil)
-	if err == youtube.ErrTooManyRequests {
+	settings := conn.State().Guilds.Settings(guildID)
+	options := &bot.QueueOptions{
+		Guild:      ctx.GuildName(),
+		GuildID:    guildID,
+		MaxPerUser: settings.MaxQueuePerUser,
+	}
+
+	var entries []state.PlaylistEntry
+	if id, ok := strings.CutPrefix(query, videoChoicePrefix); ok {
+		// The user picked a specific video using autocomplete
+		var result youtube.SearchResult
+		result, ok, err = conn.autocomplete.Lookup(ctx, id)
+		if err == nil && ok {
+			var entry state.PlaylistEntry
+			entry, err = conn.Bot().QueueResult(ctx, source.YouTubeTrack(result), ctx.Entity(), options)
+			entries = []state.PlaylistEntry{entry}
+		}
+	} else {
+		entries, err = conn.Bot().Queue(ctx, query, ctx.Entity(), options)
+	}
+	if err == bot.ErrQueueLimitReached {
+		return fmt.Sprintf("You can have at most %d songs in the queue", settings.MaxQueuePerUser), nil
+	} else if err == youtube.ErrTooManyRequests {
 		return "Too many requests made to YouTube. Try again in a short while", nil
+	} else if reply, ok := rejectedReply(conn, err); ok {
+		return reply, nil
 	} else if err != nil {
 		slog.Error("Failed to queue query results", slog.Any("error", err))
 		return "I can't do that right now. Try again in a short while", nil
@@ -58,9 +133,146 @@ func QueueAction(ctx *Context, conn *Conn) (string, error) {
 		return "I couldn't find anything for you", nil
 	}
 
-	conn.Play(guildID, voiceChannelID)
+	conn.Play(guildID, voiceChannelID, ctx.ChannelID())
+
+	if len(entries) > 1 {
+		var response strings.Builder
+		fmt.Fprintf(&response, "Queued %d songs.\n", len(entries))
+		for i, entry := range entries {
+			fmt.Fprintf(&response, "%d. **%s**\n", i+1, entry.Title)
+		}
+		return response.String(), nil
+	}
+
+	position := conn.State().Queue.Len() - len(entries) + 1
+	ctx.AddEmbed(queuedEmbed(entries[0], max(position, 1)))
+	return "", nil
+}
+
+func RadioAction(ctx *Context, conn *Conn) (string, error) {
+	station, ok := ctx.String("station")
+	if !ok {
+		stations := conn.State().Config().Stations
+		if len(stations) == 0 {
+			return "There are no saved stations. Play a station using /radio followed by the URL of its stream", nil
+		}
+
+		var response strings.Builder
+		response.WriteString("Saved stations:\n")
+		for _, station := range stations {
+			fmt.Fprintf(&response, "- **%s**\n", station.Name)
+		}
+		return response.String(), nil
+	}
+
+	guildID, voiceChannelID, err := ctx.VoiceChannel()
+	if err == ErrNotInVoiceChannel {
+		return "You must be in a voice channel to do that", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	settings := conn.State().Guilds.Settings(guildID)
+	entry, err := conn.Bot().Radio(ctx, station, ctx.Entity(), &bot.QueueOptions{
+		Guild:      ctx.GuildName(),
+		GuildID:    guildID,
+		MaxPerUser: settings.MaxQueuePerUser,
+	})
+	if err == bot.ErrQueueLimitReached {
+		return fmt.Sprintf("You can have at most %d songs in the queue", settings.MaxQueuePerUser), nil
+	} else if err == bot.ErrStationNotFound {
+		return "I couldn't find that station. Use a saved station or the URL of a stream", nil
+	} else if err != nil {
+		slog.Error("Failed to queue radio station", slog.Any("error", err))
+		return "I can't tune in right now. Try again in a short while", nil
+	}
+
+	conn.Play(guildID, voiceChannelID, ctx.ChannelID())
+
+	position := conn.State().Queue.Len()
+	ctx.AddEmbed(queuedEmbed(entry, max(position, 1)))
+	return "", nil
+}
+
+func QueueMessageAction(ctx *Context, conn *Conn) (string, error) {
+	guildID, voiceChannelID, err := ctx.VoiceChannel()
+	if err == ErrNotInVoiceChannel {
+		return "You must be in a voice channel to do that", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	message, ok := ctx.TargetMessage()
+	if !ok {
+		return "I couldn't read that message", nil
+	}
+
+	ids := messageVideoIDs(message)
+	if len(ids) == 0 {
+		return "I couldn't find any YouTube links in that message", nil
+	}
+	if len(ids) > maxMessageVideos {
+		ids = ids[:maxMessageVideos]
+	}
+
+	settings := conn.State().Guilds.Settings(guildID)
+	options := &bot.QueueOptions{
+		Guild:      ctx.GuildName(),
+		GuildID:    guildID,
+		MaxPerUser: settings.MaxQueuePerUser,
+		// Inspecting each video would take too long
+		SkipInspection: len(ids) > 1,
+	}
+
+	entries := make([]state.PlaylistEntry, 0)
+	limitReached := false
+	rejected := ""
+	for _, id := range ids {
+		entry, err := conn.Bot().QueueVideo(ctx, id, ctx.Entity(), options)
+		if err == bot.ErrQueueLimitReached {
+			limitReached = true
+			break
+		} else if err == bot.ErrVideoNotFound {
+			continue
+		} else if reply, ok := rejectedReply(conn, err); ok {
+			rejected = reply
+			continue
+		} else if err == youtube.ErrTooManyRequests {
+			return "Too many requests made to YouTube. Try again in a short while", nil
+		} else if err != nil {
+			slog.Error("Failed to queue linked video", slog.Any("error", err))
+			return "I can't do that right now. Try again in a short while", nil
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		if limitReached {
+			return fmt.Sprintf("You can have at most %d songs in the queue", settings.MaxQueuePerUser), nil
+		} else if rejected != "" && len(ids) == 1 {
+			return rejected, nil
+		}
+		return "I couldn't find the linked videos", nil
+	}
+
+	conn.Play(guildID, voiceChannelID, ctx.ChannelID())
+
+	if len(entries) == 1 && !limitReached {
+		position := conn.State().Queue.Len()
+		ctx.AddEmbed(queuedEmbed(entries[0], max(position, 1)))
+		return "", nil
+	}
 
-	return fmt.Sprintf("Queued **%s**", entries[0].Title), nil
+	var response strings.Builder
+	response.WriteString("Queued these songs.\n")
+	for i, entry := range entries {
+		fmt.Fprintf(&response, "%d. **%s**\n", i+1, entry.Title)
+	}
+	if limitReached {
+		fmt.Fprintf(&response, "You can have at most %d songs in the queue, so I skipped the rest\n", settings.MaxQueuePerUser)
+	}
+	return response.String(), nil
 }
 
 func SuggestAction(ctx *Context, conn *Conn) (string, error) {
@@ -76,7 +288,20 @@ func SuggestAction(ctx *Context, conn *Conn) (string, error) {
 		return "Missing required query parameter", nil
 	}
 
-	entries, err := conn.Bot().Suggest(ctx, ctx.Entity(), query)
+	var entries []state.PlaylistEntry
+	if id, ok := strings.CutPrefix(query, videoChoicePrefix); ok {
+		// The user picked a specific video using autocomplete
+		var result youtube.SearchResult
+		result, ok, err = conn.autocomplete.Lookup(ctx, id)
+		if err == nil && ok {
+			entries = []state.PlaylistEntry{conn.Bot().SuggestResult(source.YouTubeTrack(result), ctx.Entity())}
+		}
+	} else {
+		entries, err = conn.Bot().Suggest(ctx, ctx.Entity(), query, &bot.SuggestOptions{
+			Guild:   ctx.GuildName(),
+			GuildID: guildID,
+		})
+	}
 	if err == youtube.ErrTooManyRequests {
 		return "Too many requests made to YouTube. Try again in a short while", nil
 	} else if err != nil {
@@ -88,7 +313,7 @@ func SuggestAction(ctx *Context, conn *Conn) (string, error) {
 		return "I couldn't find anything for you", nil
 	}
 
-	conn.Play(guildID, voiceChannelID)
+	conn.Play(guildID, voiceChannelID, ctx.ChannelID())
 
 	var response strings.Builder
 	response.WriteString("I've added these songs to the list of suggestions.\n")
@@ -99,38 +324,25 @@ func SuggestAction(ctx *Context, conn *Conn) (string, error) {
 }
 
 func QueuedAction(ctx *Context, conn *Conn) (string, error) {
-	format := "{{.Index}}. {{.EntityName}} queued {{.RelativeTime}} - **{{.Title}}**\n"
-	contents, err := conn.State().Queue.Format(format, 20, false)
-	if err != nil {
-		return "", err
-	}
-	if contents == "" {
-		contents = "No songs"
-	}
-	return contents, nil
+	return PageAction(ctx, conn, "queued")
 }
 
 func SuggestionsAction(ctx *Context, conn *Conn) (string, error) {
-	format := "{{.Index}}. **{{.Title}}**\n"
-	contents, err := conn.State().Suggestions.Format(format, 20, false)
-	if err != nil {
-		return "", err
-	}
-	if contents == "" {
-		contents = "No songs"
-	}
-	return contents, nil
+	return PageAction(ctx, conn, "suggestions")
 }
 
 func RecentAction(ctx *Context, conn *Conn) (string, error) {
-	format := "{{.Index}}. {{.EntityName}} played {{.RelativeTime}} - **{{.Title}}**\n"
-	contents, err := conn.State().History.Format(format, 20, true)
+	return PageAction(ctx, conn, "recent")
+}
+
+// PageAction replies with the first page of the named playlist view.
+func PageAction(ctx *Context, conn *Conn, name string) (string, error) {
+	contents, components, err := renderPage(conn.State(), name, 0)
 	if err != nil {
 		return "", err
 	}
-	if contents == "" {
-		contents = "No songs"
-	}
+
+	ctx.SetComponents(components)
 	return contents, nil
 }
 
@@ -141,6 +353,440 @@ func StopAction(ctx *Context, conn *Conn) (string, error) {
 
 func SkipAction(ctx *Context, conn *Conn) (string, error) {
 	n, _ := ctx.Number("n")
+	// Requesters may skip their own song, but nothing more
+	if n > 1 && !conn.isDJ(ctx.GuildID(), ctx.event.Member) {
+		return "Only DJs can skip more than one song", nil
+	}
+
 	conn.Bot().SkipN(int(n))
 	return "Skipping", nil
 }
+
+func NowPlayingAction(ctx *Context, conn *Conn) (string, error) {
+	current := conn.Bot().NowPlaying()
+	if current == nil {
+		return "Nothing is playing", nil
+	}
+
+	ctx.AddEmbed(nowPlayingEmbed(*current, conn.Bot()))
+	ctx.SetComponents(playerComponents(conn.Bot()))
+	return "", nil
+}
+
+func PauseAction(ctx *Context, conn *Conn) (string, error) {
+	if conn.Bot().NowPlaying() == nil {
+		return "Nothing is playing", nil
+	}
+
+	if conn.Bot().Paused() {
+		conn.Bot().Resume()
+		return "Resuming", nil
+	}
+
+	conn.Bot().Pause()
+	return "Pausing", nil
+}
+
+func RepeatAction(ctx *Context, conn *Conn) (string, error) {
+	repeat, ok := ctx.Boolean("enabled")
+	if !ok {
+		repeat = !conn.Bot().Repeat()
+	}
+
+	conn.Bot().SetRepeat(repeat)
+	if repeat {
+		return "Repeating the current song", nil
+	}
+	return "No longer repeating the current song", nil
+}
+
+func ShuffleAction(ctx *Context, conn *Conn) (string, error) {
+	conn.Bot().Shuffle()
+	return "Shuffled the queue", nil
+}
+
+func LikeAction(ctx *Context, conn *Conn) (string, error) {
+	entry := conn.Bot().Like(ctx.Entity())
+	if entry == nil {
+		return "Nothing is playing", nil
+	}
+
+	return fmt.Sprintf("Added **%s** to liked songs", entry.Title), nil
+}
+
+func PlaylistExportAction(ctx *Context, conn *Conn) (string, error) {
+	name, ok := ctx.String("playlist")
+	if !ok {
+		name = "queue"
+	}
+
+	var playlist *state.Playlist
+	switch name {
+	case "queue":
+		playlist = conn.State().Queue
+	case "suggestions":
+		playlist = conn.State().Suggestions
+	case "history":
+		playlist = conn.State().History
+	case "likes":
+		playlist = conn.State().Likes
+	default:
+		return "Unknown playlist", nil
+	}
+
+	format := state.PlaylistFormatM3U
+	if value, ok := ctx.String("format"); ok {
+		format = state.PlaylistFormat(value)
+	}
+
+	entries := playlist.Entries()
+	if len(entries) == 0 {
+		return "No songs", nil
+	}
+
+	var buffer bytes.Buffer
+	exported, err := state.ExportPlaylist(&buffer, entries, format)
+	if err == state.ErrUnsupportedPlaylistFormat {
+		return "Unsupported playlist format", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if exported == 0 {
+		return fmt.Sprintf("None of the songs can be exported as %s", format), nil
+	}
+
+	ctx.AttachFile(fmt.Sprintf("%s.%s", name, format), format.ContentType(), &buffer)
+	if skipped := len(entries) - exported; skipped > 0 {
+		return fmt.Sprintf("Exported %d songs, skipped %d that can't be exported as %s", exported, skipped, format), nil
+	}
+	return fmt.Sprintf("Exported %d songs", exported), nil
+}
+
+func PlaylistImportAction(ctx *Context, conn *Conn) (string, error) {
+	attachment, ok := ctx.Attachment("file")
+	if !ok {
+		return "Missing required file parameter", nil
+	}
+
+	format, ok := state.PlaylistFormatFromFilename(attachment.Filename)
+	if !ok {
+		return "Unsupported playlist format. Use M3U8, XSPF or JSON", nil
+	}
+
+	if attachment.Size > maxPlaylistImportSize {
+		return "The playlist is too large", nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attachment.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code when fetching attachment: %s", res.Status)
+	}
+
+	entries, err := state.ImportPlaylist(io.LimitReader(res.Body, maxPlaylistImportSize), format)
+	if err != nil {
+		slog.Debug("Failed to parse playlist", slog.Any("error", err))
+		return "I couldn't read that playlist", nil
+	}
+
+	if len(entries) == 0 {
+		return "I couldn't find any supported songs in that playlist", nil
+	}
+
+	entity := ctx.Entity()
+	for i := range entries {
+		entries[i].Time = time.Now()
+		entries[i].AddedBy = entity
+	}
+
+	name, ok := ctx.String("playlist")
+	if !ok {
+		name = "queue"
+	}
+
+	var response strings.Builder
+	switch name {
+	case "queue":
+		settings := conn.State().Guilds.Settings(ctx.GuildID())
+		queued, rejected, err := conn.Bot().QueueEntries(entries, entity, &bot.QueueOptions{
+			GuildID:    ctx.GuildID(),
+			MaxPerUser: settings.MaxQueuePerUser,
+		})
+		if err == bot.ErrQueueLimitReached {
+			return fmt.Sprintf("You can have at most %d songs in the queue", settings.MaxQueuePerUser), nil
+		} else if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&response, "Imported %d songs to the queue\n", len(queued))
+		if rejected > 0 {
+			fmt.Fprintf(&response, "Skipped %d songs that are too long\n", rejected)
+		}
+		if len(queued)+rejected < len(entries) {
+			fmt.Fprintf(&response, "You can have at most %d songs in the queue, so I skipped the rest\n", settings.MaxQueuePerUser)
+		}
+	case "suggestions":
+		suggested := conn.Bot().SuggestEntries(entries)
+		fmt.Fprintf(&response, "Imported %d songs to the suggestions\n", len(suggested))
+		if rejected := len(entries) - len(suggested); rejected > 0 {
+			fmt.Fprintf(&response, "Skipped %d songs that are too long\n", rejected)
+		}
+	default:
+		return "Unknown playlist", nil
+	}
+
+	return response.String(), nil
+}
+
+func VoteSkipAction(ctx *Context, conn *Conn) (string, error) {
+	guildID, voiceChannelID, err := ctx.VoiceChannel()
+	botVoiceChannelID, _ := conn.VoiceChannelID()
+	if err == ErrNotInVoiceChannel || (err == nil && voiceChannelID != botVoiceChannelID) {
+		return "You must be listening to vote", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	current := conn.Bot().NowPlaying()
+	if current == nil {
+		return "Nothing is playing", nil
+	}
+
+	if conn.canSkipWithoutVote(guildID, ctx.event.Member, ctx.Entity(), current) {
+		if !conn.Bot().SkipEntry(current) {
+			return fmt.Sprintf("**%s** has already finished", current.Title), nil
+		}
+		return "Skipping", nil
+	}
+
+	settings := conn.State().Guilds.Settings(guildID)
+	required := requiredVotes(len(conn.Listeners(guildID)), settings.VoteSkipThreshold())
+	votes := conn.skipVotes.Vote(current, ctx.event.Member.User.ID)
+	if votes >= required {
+		conn.skipVotes.Reset()
+		if !conn.Bot().SkipEntry(current) {
+			return fmt.Sprintf("**%s** has already finished", current.Title), nil
+		}
+		return fmt.Sprintf("Vote passed (%d/%d). Skipping **%s**", votes, required, current.Title), nil
+	}
+
+	return fmt.Sprintf("Voted to skip **%s** (%d/%d)", current.Title, votes, required), nil
+}
+
+// settingsKeys holds the keys of the guild settings that can be changed.
+var settingsKeys = []string{"volume", "extrapolate", "lookback", "dj-role", "dj-commands", "requester-skip", "vote-skip", "announce-channel", "max-queue"}
+
+func SettingsGetAction(ctx *Context, conn *Conn) (string, error) {
+	config := conn.State().Config()
+	settings := conn.State().Guilds.Settings(ctx.GuildID())
+
+	extrapolate := "off"
+	if settings.ShouldExtrapolate(config) {
+		extrapolate = "on"
+	}
+	if settings.Extrapolate == nil {
+		extrapolate = fmt.Sprintf("default (%s)", extrapolate)
+	}
+
+	lookback := fmt.Sprintf("%d", settings.ExtrapolationLookback)
+	if settings.ExtrapolationLookback == 0 {
+		lookback = fmt.Sprintf("default (%d)", config.ExtrapolationLookback)
+	}
+
+	djRole := "none"
+	if settings.DJRole != "" {
+		djRole = fmt.Sprintf("<@&%s>", settings.DJRole)
+	}
+
+	djCommands := strings.Join(settings.DJCommands, ", ")
+	if settings.DJCommands == nil {
+		djCommands = fmt.Sprintf("default (%s)", strings.Join(conn.defaultDJCommands(), ", "))
+	} else if len(settings.DJCommands) == 0 {
+		djCommands = "none"
+	}
+
+	requesterSkip := "off"
+	if settings.AllowsRequesterSkip() {
+		requesterSkip = "on"
+	}
+
+	voteSkip := fmt.Sprintf("%g%%", settings.VoteSkipThreshold()*100)
+	if settings.VoteSkipFraction == 0 {
+		voteSkip = fmt.Sprintf("default (%s)", voteSkip)
+	}
+
+	announceChannel := "none"
+	if settings.AnnounceChannel != "" {
+		announceChannel = fmt.Sprintf("<#%s>", settings.AnnounceChannel)
+	}
+
+	maxQueue := "unlimited"
+	if settings.MaxQueuePerUser > 0 {
+		maxQueue = fmt.Sprintf("%d", settings.MaxQueuePerUser)
+	}
+
+	var response strings.Builder
+	fmt.Fprintf(&response, "**volume**: %d%%\n", settings.Volume)
+	fmt.Fprintf(&response, "**extrapolate**: %s\n", extrapolate)
+	fmt.Fprintf(&response, "**lookback**: %s\n", lookback)
+	fmt.Fprintf(&response, "**dj-role**: %s\n", djRole)
+	fmt.Fprintf(&response, "**dj-commands**: %s\n", djCommands)
+	fmt.Fprintf(&response, "**requester-skip**: %s\n", requesterSkip)
+	fmt.Fprintf(&response, "**vote-skip**: %s\n", voteSkip)
+	fmt.Fprintf(&response, "**announce-channel**: %s\n", announceChannel)
+	fmt.Fprintf(&response, "**max-queue**: %s\n", maxQueue)
+	return response.String(), nil
+}
+
+func SettingsSetAction(ctx *Context, conn *Conn) (string, error) {
+	key, ok := ctx.String("key")
+	if !ok {
+		return "Missing required key parameter", nil
+	}
+
+	value, ok := ctx.String("value")
+	if !ok {
+		return "Missing required value parameter", nil
+	}
+	value = strings.TrimSpace(value)
+	reset := strings.EqualFold(value, "default") || strings.EqualFold(value, "none")
+
+	guildID := ctx.GuildID()
+	settings := conn.State().Guilds.Settings(guildID)
+	defaults := state.DefaultGuildSettings()
+
+	switch key {
+	case "volume":
+		if reset {
+			settings.Volume = defaults.Volume
+			break
+		}
+
+		volume, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil || volume < 0 || volume > 200 {
+			return "The volume must be a percentage between 0 and 200", nil
+		}
+		settings.Volume = volume
+	case "extrapolate":
+		if reset {
+			settings.Extrapolate = defaults.Extrapolate
+			break
+		}
+
+		extrapolate, err := parseBool(value)
+		if err != nil {
+			return "The value must be either on or off", nil
+		}
+		settings.Extrapolate = &extrapolate
+	case "lookback":
+		if reset {
+			settings.ExtrapolationLookback = defaults.ExtrapolationLookback
+			break
+		}
+
+		lookback, err := strconv.Atoi(value)
+		if err != nil || lookback < 1 {
+			return "The lookback must be a positive number", nil
+		}
+		settings.ExtrapolationLookback = lookback
+	case "dj-role":
+		if reset {
+			settings.DJRole = defaults.DJRole
+			break
+		}
+
+		roleID, ok := ctx.ResolveRole(value)
+		if !ok {
+			return "I couldn't find that role", nil
+		}
+		settings.DJRole = roleID
+	case "dj-commands":
+		if strings.EqualFold(value, "default") {
+			settings.DJCommands = defaults.DJCommands
+			break
+		}
+
+		djCommands := make([]string, 0)
+		if !strings.EqualFold(value, "none") {
+			for _, name := range strings.Split(value, ",") {
+				name = strings.TrimPrefix(strings.TrimSpace(name), "/")
+				if !conn.commandExists(name) {
+					return fmt.Sprintf("Unknown command %s", name), nil
+				}
+				djCommands = append(djCommands, name)
+			}
+		}
+		settings.DJCommands = djCommands
+	case "requester-skip":
+		if reset {
+			settings.RequesterSkip = defaults.RequesterSkip
+			break
+		}
+
+		requesterSkip, err := parseBool(value)
+		if err != nil {
+			return "The value must be either on or off", nil
+		}
+		settings.RequesterSkip = &requesterSkip
+	case "vote-skip":
+		if reset {
+			settings.VoteSkipFraction = defaults.VoteSkipFraction
+			break
+		}
+
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percentage <= 0 || percentage > 100 {
+			return "The vote skip threshold must be a percentage between 1 and 100", nil
+		}
+		settings.VoteSkipFraction = percentage / 100
+	case "announce-channel":
+		if reset {
+			settings.AnnounceChannel = defaults.AnnounceChannel
+			break
+		}
+
+		channelID, ok := ctx.ResolveChannel(value)
+		if !ok {
+			return "I couldn't find that channel", nil
+		}
+		settings.AnnounceChannel = channelID
+	case "max-queue":
+		if reset {
+			settings.MaxQueuePerUser = defaults.MaxQueuePerUser
+			break
+		}
+
+		max, err := strconv.Atoi(value)
+		if err != nil || max < 0 {
+			return "The max queue length must be a positive number, or 0 for no limit", nil
+		}
+		settings.MaxQueuePerUser = max
+	default:
+		return "Unknown setting", nil
+	}
+
+	conn.State().Guilds.SetSettings(guildID, settings)
+	return fmt.Sprintf("Updated **%s**", key), nil
+}
+
+// parseBool parses a human-friendly boolean value.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "yes", "enabled":
+		return true, nil
+	case "off", "no", "disabled":
+		return false, nil
+	default:
+		return strconv.ParseBool(value)
+	}
+}
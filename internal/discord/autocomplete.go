@@ -0,0 +1,211 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/timeutil"
+	"github.com/AlexGustafsson/clabbe/internal/youtube"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// videoChoicePrefix is the prefix of autocomplete choice values that refer
+	// to a specific YouTube video.
+	videoChoicePrefix = "youtube:"
+	// maxAutocompleteChoices is the maximum number of choices Discord accepts.
+	maxAutocompleteChoices = 25
+	// maxChoiceNameLength is the maximum length of a choice's name.
+	maxChoiceNameLength = 100
+	// minAutocompleteQueryLength is the shortest query to search for.
+	minAutocompleteQueryLength = 3
+	// autocompleteDebounce is the time to wait for a user to stop typing before
+	// searching.
+	autocompleteDebounce = 300 * time.Millisecond
+	// autocompleteCacheTTL is the time search results are cached.
+	autocompleteCacheTTL = 5 * time.Minute
+)
+
+// cachedSearch holds cached search results.
+type cachedSearch struct {
+	results []youtube.SearchResult
+	expires time.Time
+}
+
+// cachedResult holds a cached search result.
+type cachedResult struct {
+	result  youtube.SearchResult
+	expires time.Time
+}
+
+// autocompleter suggests YouTube videos as a user types a query.
+type autocompleter struct {
+	client *youtube.SearchClient
+
+	mutex sync.Mutex
+	// requests holds the latest request number of each user. Used to debounce
+	// requests.
+	requests map[string]uint64
+	// counter is used to number requests.
+	counter uint64
+	// searches holds cached search results, keyed by the normalized query.
+	searches map[string]cachedSearch
+	// results holds cached results, keyed by video id.
+	results map[string]cachedResult
+}
+
+func newAutocompleter() *autocompleter {
+	return &autocompleter{
+		client:   youtube.NewSearchClient(),
+		requests: make(map[string]uint64),
+		searches: make(map[string]cachedSearch),
+		results:  make(map[string]cachedResult),
+	}
+}
+
+// Choices returns choices for the user's query. Returns false if the user
+// has made a newer request while waiting for them to stop typing, in which
+// case the request should be left unanswered.
+func (a *autocompleter) Choices(ctx context.Context, userID string, query string) ([]*discordgo.ApplicationCommandOptionChoice, bool, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if len(query) < minAutocompleteQueryLength {
+		return []*discordgo.ApplicationCommandOptionChoice{}, true, nil
+	}
+
+	results, ok := a.cachedSearch(query)
+	if !ok {
+		if !a.debounce(ctx, userID) {
+			return nil, false, nil
+		}
+
+		var err error
+		results, err = a.client.SearchN(ctx, query, maxAutocompleteChoices, nil)
+		if err != nil {
+			return nil, true, err
+		}
+		a.cacheSearch(query, results)
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, result := range results {
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  choiceName(result),
+			Value: videoChoicePrefix + result.ID,
+		})
+	}
+
+	return choices, true, nil
+}
+
+// Lookup returns the search result for a video id previously returned as a
+// choice. If the result is no longer cached, the id is searched for.
+func (a *autocompleter) Lookup(ctx context.Context, id string) (youtube.SearchResult, bool, error) {
+	a.mutex.Lock()
+	cached, ok := a.results[id]
+	a.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.result, true, nil
+	}
+
+	return a.client.Lookup(ctx, id)
+}
+
+// debounce waits for the user to stop typing. Returns false if the user made
+// a newer request in the meantime or if ctx is done.
+func (a *autocompleter) debounce(ctx context.Context, userID string) bool {
+	a.mutex.Lock()
+	a.counter++
+	request := a.counter
+	a.requests[userID] = request
+	a.mutex.Unlock()
+
+	select {
+	case <-time.After(autocompleteDebounce):
+	case <-ctx.Done():
+		return false
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.requests[userID] != request {
+		return false
+	}
+	delete(a.requests, userID)
+	return true
+}
+
+func (a *autocompleter) cachedSearch(query string) ([]youtube.SearchResult, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	cached, ok := a.searches[query]
+	if !ok || time.Now().After(cached.expires) {
+		return nil, false
+	}
+
+	return cached.results, true
+}
+
+func (a *autocompleter) cacheSearch(query string, results []youtube.SearchResult) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+
+	// Prune expired entries
+	for key, cached := range a.searches {
+		if now.After(cached.expires) {
+			delete(a.searches, key)
+		}
+	}
+	for key, cached := range a.results {
+		if now.After(cached.expires) {
+			delete(a.results, key)
+		}
+	}
+
+	expires := now.Add(autocompleteCacheTTL)
+	a.searches[query] = cachedSearch{
+		results: results,
+		expires: expires,
+	}
+	for _, result := range results {
+		a.results[result.ID] = cachedResult{
+			result:  result,
+			expires: expires,
+		}
+	}
+}
+
+// choiceName returns the label of a search result, such as
+// "Title - Channel (3:32)". Long titles are truncated to fit.
+func choiceName(result youtube.SearchResult) string {
+	suffix := ""
+	if result.Channel != "" {
+		suffix += " - " + result.Channel
+	}
+	if result.Duration > 0 {
+		suffix += fmt.Sprintf(" (%s)", timeutil.FormatDuration(result.Duration))
+	} else {
+		suffix += " (live)"
+	}
+
+	title := []rune(result.Title)
+	available := maxChoiceNameLength - len([]rune(suffix))
+	if len(title) > available {
+		title = append(title[:max(available-1, 0)], '…')
+	}
+
+	name := []rune(string(title) + suffix)
+	if len(name) > maxChoiceNameLength {
+		name = name[:maxChoiceNameLength]
+	}
+	return string(name)
+}
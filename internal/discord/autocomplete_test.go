@@ -0,0 +1,54 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlexGustafsson/clabbe/internal/youtube"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChoiceName(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Result   youtube.SearchResult
+		Expected string
+	}{
+		{
+			Name: "video",
+			Result: youtube.SearchResult{
+				Title:    "Never Gonna Give You Up",
+				Channel:  "Rick Astley",
+				Duration: 3*time.Minute + 33*time.Second,
+			},
+			Expected: "Never Gonna Give You Up - Rick Astley (3:33)",
+		},
+		{
+			Name: "live",
+			Result: youtube.SearchResult{
+				Title:   "lofi hip hop radio",
+				Channel: "Lofi Girl",
+			},
+			Expected: "lofi hip hop radio - Lofi Girl (live)",
+		},
+		{
+			Name: "long title",
+			Result: youtube.SearchResult{
+				Title:    strings.Repeat("å", 120),
+				Channel:  "Channel",
+				Duration: time.Minute,
+			},
+			Expected: strings.Repeat("å", 82) + "… - Channel (1:00)",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			actual := choiceName(testCase.Result)
+			assert.Equal(t, testCase.Expected, actual)
+			assert.LessOrEqual(t, utf8.RuneCountInString(actual), maxChoiceNameLength)
+		})
+	}
+}
@@ -3,11 +3,16 @@ package discord
 import (
 	"github.com/AlexGustafsson/clabbe/internal/bot"
 	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/bwmarrin/discordgo"
 )
 
 // Command exposes functionality as a Discord command.
 type Command struct {
-	Name        string
+	Name string
+	// Type defaults to a slash command.
+	Type CommandType
+	// Description is required for slash commands. Other commands have no
+	// description.
 	Description string
 	Options     []Option
 	// Action is the function to invoke whenever the command is invoked.
@@ -17,8 +22,32 @@ type Command struct {
 	// EnabledFunc returns true if the command is enabled.
 	// A nil EnabledFunc implicitly enables the command.
 	EnabledFunc func(*state.State, *bot.Bot) bool
+	// DJ marks the command as requiring the guild's DJ role, if one is
+	// configured. Guilds may override which commands require the role.
+	DJ bool
+	// AllowRequester allows the member who requested the currently playing
+	// song to invoke a DJ command without the DJ role.
+	AllowRequester bool
+	// Permissions holds the Discord permissions, such as
+	// discordgo.PermissionManageServer, a member must have to invoke the
+	// command. A zero value allows anyone to invoke the command.
+	Permissions int64
+	// Subcommands holds the subcommands of the command. A command with
+	// subcommands cannot be invoked on its own, its Action and Options are
+	// ignored.
+	Subcommands []Command
 }
 
+type CommandType int
+
+const (
+	// CommandTypeChat is a slash command.
+	CommandTypeChat CommandType = iota
+	// CommandTypeMessage is shown in the context menu of messages. The action
+	// can access the message using Context.TargetMessage.
+	CommandTypeMessage
+)
+
 // Option defines an option to a command.
 type Option struct {
 	Name        string
@@ -26,6 +55,11 @@ type Option struct {
 	// Type defaults to string.
 	Type     OptionType
 	Required bool
+	// Choices optionally restricts a string option to a set of values.
+	Choices []string
+	// Autocomplete suggests YouTube videos as the user types. Cannot be
+	// combined with Choices.
+	Autocomplete bool
 	// EnabledFunc returns true if the option is enabled.
 	// A nil EnabledFunc implicitly enables the command.
 	EnabledFunc func(*state.State, *bot.Bot) bool
@@ -37,6 +71,7 @@ const (
 	OptionTypeString = iota << 1
 	OptionTypeNumber
 	OptionTypeBoolean
+	OptionTypeAttachment
 )
 
 // TODO:
@@ -71,9 +106,21 @@ var commands = []Command{
 		Action:      QueueAction,
 		Options: []Option{
 			{
-				Name:        "query",
-				Description: "YouTube search query",
-				Required:    true,
+				Name:         "query",
+				Description:  "YouTube search query or link",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	},
+	{
+		Name:        "radio",
+		Description: "Play a radio station",
+		Action:      RadioAction,
+		Options: []Option{
+			{
+				Name:        "station",
+				Description: "Name of a saved station or URL of a stream. Lists the saved stations if not set",
 			},
 		},
 	},
@@ -88,9 +135,10 @@ var commands = []Command{
 		Action:      SuggestAction,
 		Options: []Option{
 			{
-				Name:        "query",
-				Description: "LLM search query",
-				Required:    true,
+				Name:         "query",
+				Description:  "LLM search query",
+				Required:     true,
+				Autocomplete: true,
 			},
 		},
 		EnabledFunc: func(s *state.State, b *bot.Bot) bool {
@@ -110,15 +158,89 @@ var commands = []Command{
 		Description: "Print recently played songs",
 		Action:      RecentAction,
 	},
+	{
+		Name:        "playlist",
+		Description: "Import or export playlists",
+		Subcommands: []Command{
+			{
+				Name:        "export",
+				Description: "Export a playlist as a file",
+				Action:      PlaylistExportAction,
+				Options: []Option{
+					{
+						Name:        "playlist",
+						Description: "Playlist to export. Defaults to queue",
+						Choices:     []string{"queue", "suggestions", "history", "likes"},
+					},
+					{
+						Name:        "format",
+						Description: "File format. Defaults to m3u8",
+						Choices:     []string{"m3u8", "xspf", "json"},
+					},
+				},
+			},
+			{
+				Name:        "import",
+				Description: "Import songs from a playlist file",
+				Action:      PlaylistImportAction,
+				Options: []Option{
+					{
+						Name:        "file",
+						Description: "M3U8, XSPF or JSON playlist",
+						Type:        OptionTypeAttachment,
+						Required:    true,
+					},
+					{
+						Name:        "playlist",
+						Description: "Playlist to import to. Defaults to queue",
+						Choices:     []string{"queue", "suggestions"},
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "settings",
+		Description: "Manage the bot's settings for this server",
+		Permissions: discordgo.PermissionManageServer,
+		Subcommands: []Command{
+			{
+				Name:        "get",
+				Description: "Print the current settings",
+				Action:      SettingsGetAction,
+			},
+			{
+				Name:        "set",
+				Description: "Change a setting",
+				Action:      SettingsSetAction,
+				Options: []Option{
+					{
+						Name:        "key",
+						Description: "Setting to change",
+						Required:    true,
+						Choices:     settingsKeys,
+					},
+					{
+						Name:        "value",
+						Description: "New value. Use \"default\" to reset the setting",
+						Required:    true,
+					},
+				},
+			},
+		},
+	},
 	{
 		Name:        "stop",
 		Description: "Disconnect the bot",
 		Action:      StopAction,
+		DJ:          true,
 	},
 	{
-		Name:        "skip",
-		Description: "Skip the current song",
-		Action:      SkipAction,
+		Name:           "skip",
+		Description:    "Skip the current song",
+		Action:         SkipAction,
+		DJ:             true,
+		AllowRequester: true,
 		Options: []Option{
 			{
 				Name:        "n",
@@ -127,4 +249,49 @@ var commands = []Command{
 			},
 		},
 	},
+	{
+		Name:        "nowplaying",
+		Description: "Show the currently playing song",
+		Action:      NowPlayingAction,
+	},
+	{
+		Name:        "pause",
+		Description: "Pause or resume playback",
+		Action:      PauseAction,
+		DJ:          true,
+	},
+	{
+		Name:        "repeat",
+		Description: "Repeat the current song",
+		Action:      RepeatAction,
+		DJ:          true,
+		Options: []Option{
+			{
+				Name:        "enabled",
+				Description: "Whether or not to repeat. Toggles if not set",
+				Type:        OptionTypeBoolean,
+			},
+		},
+	},
+	{
+		Name:        "shuffle",
+		Description: "Shuffle the queue",
+		Action:      ShuffleAction,
+		DJ:          true,
+	},
+	{
+		Name:        "like",
+		Description: "Add the current song to the liked songs",
+		Action:      LikeAction,
+	},
+	{
+		Name:   "Queue with Clabbe",
+		Type:   CommandTypeMessage,
+		Action: QueueMessageAction,
+	},
+	{
+		Name:        "voteskip",
+		Description: "Vote to skip the current song",
+		Action:      VoteSkipAction,
+	},
 }
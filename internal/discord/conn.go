@@ -3,6 +3,8 @@ package discord
 import (
 	"context"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlexGustafsson/clabbe/internal/bot"
@@ -16,7 +18,31 @@ type Conn struct {
 	bot     *bot.Bot
 	discord *discordgo.Session
 
+	// voiceMutex guards the voice fields below.
+	voiceMutex  sync.Mutex
 	isConnected bool
+	// guildID is the id of the guild the bot is playing in.
+	guildID string
+	// voiceChannelID is the id of the voice channel the bot is connected to.
+	voiceChannelID string
+	// textChannelID is the id of the text channel playback was started from.
+	textChannelID string
+	voice         *discordgo.VoiceConnection
+	// idleTimer disconnects the bot once it has been alone for the configured
+	// idle timeout. Nil while there are listeners.
+	idleTimer *time.Timer
+	// pausedWhileAlone is true if playback was paused as there were no
+	// listeners.
+	pausedWhileAlone bool
+	// idle holds the channel the bot left due to inactivity, if any. Playback
+	// is resumed once a user joins it.
+	idle *idleChannel
+	// leaving holds the channel the bot is leaving due to inactivity, if any.
+	leaving *idleChannel
+
+	skipVotes skipVotes
+
+	autocomplete *autocompleter
 
 	commands map[string]Command
 }
@@ -28,11 +54,13 @@ func Dial(state *state.State, bot *bot.Bot) (*Conn, error) {
 		state: state,
 		bot:   bot,
 
+		autocomplete: newAutocompleter(),
+
 		commands: make(map[string]Command),
 	}
 
 	var err error
-	conn.discord, err = discordgo.New("Bot " + state.Config.DiscordBotToken)
+	conn.discord, err = discordgo.New("Bot " + state.Config().DiscordBotToken)
 	if err != nil {
 		return nil, err
 	}
@@ -52,34 +80,44 @@ func Dial(state *state.State, bot *bot.Bot) (*Conn, error) {
 			}
 		}
 
-		options := make([]*discordgo.ApplicationCommandOption, 0)
-		for _, o := range command.Options {
-			if o.EnabledFunc != nil {
-				if !o.EnabledFunc(state, bot) {
-					continue
+		options := conn.applicationCommandOptions(command.Options)
+		if len(command.Subcommands) > 0 {
+			options = make([]*discordgo.ApplicationCommandOption, 0)
+			for _, subcommand := range command.Subcommands {
+				if subcommand.EnabledFunc != nil {
+					if !subcommand.EnabledFunc(state, bot) {
+						continue
+					}
 				}
-			}
 
-			t := discordgo.ApplicationCommandOptionString
-			if o.Type == OptionTypeNumber {
-				t = discordgo.ApplicationCommandOptionNumber
-			} else if o.Type == OptionTypeBoolean {
-				t = discordgo.ApplicationCommandOptionBoolean
+				options = append(options, &discordgo.ApplicationCommandOption{
+					Name:        subcommand.Name,
+					Description: subcommand.Description,
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options:     conn.applicationCommandOptions(subcommand.Options),
+				})
 			}
-			options = append(options, &discordgo.ApplicationCommandOption{
-				Name:        o.Name,
-				Description: o.Description,
-				Type:        t,
-				Required:    o.Required,
-			})
+		}
+
+		var permissions *int64
+		if command.Permissions != 0 {
+			permissions = &command.Permissions
+		}
+
+		t := discordgo.ChatApplicationCommand
+		if command.Type == CommandTypeMessage {
+			// Context menu commands take no options
+			t = discordgo.MessageApplicationCommand
+			options = nil
 		}
 
 		slog.Debug("Creating command", slog.String("command", command.Name))
 		_, err := conn.discord.ApplicationCommandCreate(conn.discord.State.User.ID, "", &discordgo.ApplicationCommand{
-			Name:        command.Name,
-			Type:        discordgo.ChatApplicationCommand,
-			Description: command.Description,
-			Options:     options,
+			Name:                     command.Name,
+			Type:                     t,
+			Description:              command.Description,
+			Options:                  options,
+			DefaultMemberPermissions: permissions,
 		})
 		if err != nil {
 			conn.discord.Close()
@@ -104,21 +142,111 @@ func Dial(state *state.State, bot *bot.Bot) (*Conn, error) {
 		slog.Warn("Failed to get application commands - skipping clean of old commands")
 	}
 
-	// Add a handler for all command interactions. The
-	conn.discord.AddHandler(conn.handleCommandInvocation)
+	// Add a handler for all command and component interactions
+	conn.discord.AddHandler(conn.handleInteraction)
+	conn.discord.AddHandler(conn.handleVoiceStateUpdate)
 
 	slog.Info("Bot started")
 	return conn, nil
 }
 
+// applicationCommandOptions returns the Discord representation of the
+// enabled options.
+func (c *Conn) applicationCommandOptions(options []Option) []*discordgo.ApplicationCommandOption {
+	result := make([]*discordgo.ApplicationCommandOption, 0)
+	for _, o := range options {
+		if o.EnabledFunc != nil {
+			if !o.EnabledFunc(c.state, c.bot) {
+				continue
+			}
+		}
+
+		t := discordgo.ApplicationCommandOptionString
+		if o.Type == OptionTypeNumber {
+			t = discordgo.ApplicationCommandOptionNumber
+		} else if o.Type == OptionTypeBoolean {
+			t = discordgo.ApplicationCommandOptionBoolean
+		} else if o.Type == OptionTypeAttachment {
+			t = discordgo.ApplicationCommandOptionAttachment
+		}
+
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		for _, choice := range o.Choices {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  choice,
+				Value: choice,
+			})
+		}
+
+		result = append(result, &discordgo.ApplicationCommandOption{
+			Name:         o.Name,
+			Description:  o.Description,
+			Type:         t,
+			Required:     o.Required,
+			Choices:      choices,
+			Autocomplete: o.Autocomplete,
+		})
+	}
+	return result
+}
+
+// handleInteraction dispatches an interaction to the appropriate handler.
+func (c *Conn) handleInteraction(session *discordgo.Session, event *discordgo.InteractionCreate) {
+	switch event.Type {
+	case discordgo.InteractionApplicationCommand:
+		c.handleCommandInvocation(session, event)
+	case discordgo.InteractionMessageComponent:
+		c.handleComponentInteraction(session, event)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		c.handleAutocomplete(session, event)
+	default:
+		slog.Debug("Ignoring unsupported interaction", slog.Any("type", event.Type))
+	}
+}
+
 // handleCommandInvocation handles a command being invocated.
 func (c *Conn) handleCommandInvocation(session *discordgo.Session, event *discordgo.InteractionCreate) {
-	commandName := event.ApplicationCommandData().Name
-	slog.Debug("Got command request", slog.String("name", commandName))
+	data := event.ApplicationCommandData()
+	slog.Debug("Got command request", slog.String("name", data.Name))
 
-	command, ok := c.commands[commandName]
+	command, ok := c.commands[data.Name]
 	if !ok {
-		slog.Warn("Got command interaction for unknown command", slog.String("name", commandName))
+		slog.Warn("Got command interaction for unknown command", slog.String("name", data.Name))
+		return
+	}
+
+	// Resolve the invoked subcommand, if any. Subcommands inherit the
+	// permissions of their parent
+	options := data.Options
+	name := command.Name
+	permissions := command.Permissions
+	if len(command.Subcommands) > 0 {
+		if len(options) == 0 || options[0].Type != discordgo.ApplicationCommandOptionSubCommand {
+			slog.Warn("Got command interaction without subcommand", slog.String("name", data.Name))
+			return
+		}
+
+		ok := false
+		for _, subcommand := range command.Subcommands {
+			if subcommand.Name == options[0].Name {
+				command = subcommand
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			slog.Warn("Got command interaction for unknown subcommand", slog.String("name", data.Name), slog.String("subcommand", options[0].Name))
+			return
+		}
+
+		options = options[0].Options
+		name += " " + command.Name
+		permissions |= command.Permissions
+	}
+
+	if denial, ok := c.authorize(event, name, command, permissions); !ok {
+		slog.Debug("Denied command invocation", slog.String("name", name))
+		c.respondEphemeral(session, event, denial)
 		return
 	}
 
@@ -132,26 +260,223 @@ func (c *Conn) handleCommandInvocation(session *discordgo.Session, event *discor
 		return
 	}
 
+	commandContext, reply, err := c.invoke(session, event, command, options)
+	if err != nil {
+		slog.Error("Failed to handle command", slog.Any("error", err))
+		session.FollowupMessageCreate(event.Interaction, false, &discordgo.WebhookParams{
+			Content: "An error occured. Try again in a little while.",
+		})
+		return
+	}
+
+	// Update the response with the reply from the action. Long replies are
+	// split into multiple messages, the last of which holds any attachments
+	chunks := splitMessage(reply, maxMessageLength)
+	for i, chunk := range chunks {
+		params := &discordgo.WebhookParams{
+			Content: chunk,
+		}
+		if i == len(chunks)-1 {
+			params.Files = commandContext.files
+			params.Embeds = commandContext.embeds
+			params.Components = commandContext.components
+		}
+		if _, err := session.FollowupMessageCreate(event.Interaction, false, params); err != nil {
+			slog.Error("Failed to reply to command", slog.Any("error", err))
+			return
+		}
+	}
+}
+
+// handleAutocomplete handles a user typing in an option with autocomplete.
+func (c *Conn) handleAutocomplete(session *discordgo.Session, event *discordgo.InteractionCreate) {
+	data := event.ApplicationCommandData()
+
+	command, ok := c.commands[data.Name]
+	if !ok {
+		slog.Warn("Got autocomplete interaction for unknown command", slog.String("name", data.Name))
+		return
+	}
+
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, option := range data.Options {
+		if option.Focused {
+			focused = option
+			break
+		}
+	}
+	if focused == nil {
+		return
+	}
+
+	autocomplete := false
+	for _, option := range command.Options {
+		if option.Name == focused.Name {
+			autocomplete = option.Autocomplete
+			break
+		}
+	}
+	if !autocomplete || event.Member == nil {
+		return
+	}
+
+	// Discord requires a response within three seconds
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	choices, ok, err := c.autocomplete.Choices(ctx, event.Member.User.ID, focused.StringValue())
+	if err != nil {
+		slog.Warn("Failed to autocomplete", slog.Any("error", err))
+		choices = []*discordgo.ApplicationCommandOptionChoice{}
+	} else if !ok {
+		// The user has kept typing, let the newer request respond
+		return
+	}
+
+	if err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	}); err != nil {
+		slog.Error("Failed to respond to autocomplete", slog.Any("error", err))
+	}
+}
+
+// handleComponentInteraction handles a message component, such as a button,
+// being used. Components invoke the command named by their custom id. The
+// message the component belongs to is refreshed to reflect the current
+// playback and the reply of the command is sent only to the invoking member.
+func (c *Conn) handleComponentInteraction(session *discordgo.Session, event *discordgo.InteractionCreate) {
+	data := event.MessageComponentData()
+	slog.Debug("Got component interaction", slog.String("customId", data.CustomID))
+
+	if strings.HasPrefix(data.CustomID, pageComponentPrefix) {
+		c.handlePageInteraction(session, event, data.CustomID)
+		return
+	}
+
+	name, ok := strings.CutPrefix(data.CustomID, commandComponentPrefix)
+	if !ok {
+		slog.Warn("Got component interaction for unknown component", slog.String("customId", data.CustomID))
+		return
+	}
+
+	command, ok := c.commands[name]
+	if !ok || len(command.Subcommands) > 0 {
+		slog.Warn("Got component interaction for unknown command", slog.String("name", name))
+		return
+	}
+
+	if denial, ok := c.authorize(event, name, command, command.Permissions); !ok {
+		slog.Debug("Denied component interaction", slog.String("name", name))
+		c.respondEphemeral(session, event, denial)
+		return
+	}
+
+	if err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		slog.Error("Failed to acknowledge component interaction", slog.Any("error", err))
+		return
+	}
+
+	_, reply, err := c.invoke(session, event, command, nil)
+	if err != nil {
+		slog.Error("Failed to handle component interaction", slog.Any("error", err))
+		reply = "An error occured. Try again in a little while."
+	}
+
+	// Refresh the now playing message
+	embeds := []*discordgo.MessageEmbed{}
+	components := []discordgo.MessageComponent{}
+	if current := c.bot.NowPlaying(); current != nil {
+		embeds = append(embeds, nowPlayingEmbed(*current, c.bot))
+		components = playerComponents(c.bot)
+	}
+	if _, err := session.InteractionResponseEdit(event.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &embeds,
+		Components: &components,
+	}); err != nil {
+		slog.Error("Failed to update message", slog.Any("error", err))
+	}
+
+	if reply != "" {
+		session.FollowupMessageCreate(event.Interaction, false, &discordgo.WebhookParams{
+			Content: reply,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	}
+}
+
+// handlePageInteraction handles a pagination component being used by
+// replacing the message with the requested page.
+func (c *Conn) handlePageInteraction(session *discordgo.Session, event *discordgo.InteractionCreate, customID string) {
+	name, page, ok := parsePageToken(customID)
+	if !ok {
+		slog.Warn("Got invalid page token", slog.String("customId", customID))
+		return
+	}
+
+	// Views are named after the command that shows them. Respect the
+	// command's permissions and whether or not it's enabled
+	command, ok := c.commands[name]
+	if !ok {
+		slog.Warn("Got page interaction for unknown command", slog.String("name", name))
+		return
+	}
+
+	if denial, ok := c.authorize(event, name, command, command.Permissions); !ok {
+		slog.Debug("Denied page interaction", slog.String("name", name))
+		c.respondEphemeral(session, event, denial)
+		return
+	}
+
+	contents, components, err := renderPage(c.state, name, page)
+	if err != nil {
+		slog.Error("Failed to render page", slog.Any("error", err))
+		c.respondEphemeral(session, event, "An error occured. Try again in a little while.")
+		return
+	}
+
+	if err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    contents,
+			Components: components,
+		},
+	}); err != nil {
+		slog.Error("Failed to update page", slog.Any("error", err))
+	}
+}
+
+// invoke invokes the command's action.
+func (c *Conn) invoke(session *discordgo.Session, event *discordgo.InteractionCreate, command Command, options []*discordgo.ApplicationCommandInteractionDataOption) (*Context, string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Invoke the appropriate action
-	reply, err := command.Action(&Context{
+	commandContext := &Context{
 		Context: ctx,
 		session: session,
 		event:   event,
-	}, c)
-	if err != nil {
-		slog.Error("Failed to handle command", slog.Any("error", err))
-		session.FollowupMessageCreate(event.Interaction, false, &discordgo.WebhookParams{
-			Content: "An error occured. Try again in a little while.",
-		})
+		options: options,
 	}
+	reply, err := command.Action(commandContext, c)
+	return commandContext, reply, err
+}
 
-	// Update the response with the reply from the action
-	session.FollowupMessageCreate(event.Interaction, false, &discordgo.WebhookParams{
-		Content: reply,
-	})
+// respondEphemeral responds to the interaction with a message only visible to
+// the invoking member.
+func (c *Conn) respondEphemeral(session *discordgo.Session, event *discordgo.InteractionCreate, content string) {
+	if err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		slog.Error("Failed to respond to interaction", slog.Any("error", err))
+	}
 }
 
 // Bot returns the Bot this connection is for.
@@ -174,31 +499,113 @@ func (c *Conn) Close() error {
 	return c.discord.Close()
 }
 
-// Play starts playing in the specified channel.
-func (c *Conn) Play(guildID string, voiceChannelID string) {
-	// TODO: Doesn't handle channel changes?
-	go func() {
-		if c.isConnected {
-			return
+// Listeners returns the ids of the users, other than bots, that are listening
+// in the voice channel the bot is connected to in the guild.
+func (c *Conn) Listeners(guildID string) []string {
+	voiceChannelID, ok := c.VoiceChannelID()
+	if !ok {
+		return nil
+	}
+
+	return c.listeners(guildID, voiceChannelID)
+}
+
+// listeners returns the ids of the users, other than bots, that are in the
+// voice channel.
+func (c *Conn) listeners(guildID string, voiceChannelID string) []string {
+	guild, err := c.discord.State.Guild(guildID)
+	if err != nil {
+		return nil
+	}
+
+	listeners := make([]string, 0)
+	for _, voiceState := range guild.VoiceStates {
+		if voiceState.ChannelID != voiceChannelID || voiceState.UserID == c.discord.State.User.ID {
+			continue
 		}
 
+		member := voiceState.Member
+		if member == nil {
+			member, _ = c.discord.State.Member(guildID, voiceState.UserID)
+		}
+		if member != nil && member.User != nil && member.User.Bot {
+			continue
+		}
+
+		listeners = append(listeners, voiceState.UserID)
+	}
+
+	return listeners
+}
+
+// VoiceChannelID returns the id of the voice channel the bot is connected to.
+func (c *Conn) VoiceChannelID() (string, bool) {
+	c.voiceMutex.Lock()
+	defer c.voiceMutex.Unlock()
+
+	return c.voiceChannelID, c.isConnected
+}
+
+// Play starts playing in the specified voice channel. Playback is announced
+// in the text channel, unless the guild has configured an announce channel.
+// If the bot is already playing, this is a no-op. See Move.
+func (c *Conn) Play(guildID string, voiceChannelID string, textChannelID string) {
+	c.voiceMutex.Lock()
+	defer c.voiceMutex.Unlock()
+
+	c.idle = nil
+	if c.isConnected {
+		return
+	}
+
+	c.isConnected = true
+	c.guildID = guildID
+	c.voiceChannelID = voiceChannelID
+	c.textChannelID = textChannelID
+
+	go func() {
 		slog.Debug("Connecting bot to voice channel", slog.String("guildId", guildID), slog.String("voiceChannelID", voiceChannelID))
 		channel, err := c.discord.ChannelVoiceJoin(guildID, voiceChannelID, false, true)
 		if err != nil {
 			slog.Error("Failed to join channel", slog.Any("error", err))
+			c.voiceMutex.Lock()
+			c.isConnected = false
+			c.voiceChannelID = ""
+			c.voiceMutex.Unlock()
 			return
 		}
 		defer func() {
-			channel.Speaking(false)
-			channel.Disconnect()
+			// The connection may have been replaced when reconnecting
+			c.voiceMutex.Lock()
+			channel := c.voice
+			c.voiceMutex.Unlock()
+			if channel != nil {
+				channel.Speaking(false)
+				channel.Disconnect()
+			}
+
+			c.voiceMutex.Lock()
 			c.isConnected = false
+			c.voiceChannelID = ""
+			c.voice = nil
+			c.idle = c.leaving
+			c.leaving = nil
+			c.pausedWhileAlone = false
+			if c.idleTimer != nil {
+				c.idleTimer.Stop()
+				c.idleTimer = nil
+			}
+			c.voiceMutex.Unlock()
 			c.discord.UpdateStatusComplex(discordgo.UpdateStatusData{
 				Activities: []*discordgo.Activity{},
 			})
 		}()
-		c.isConnected = true
 
-		if c.state.Config.LogLevel == slog.LevelDebug {
+		c.voiceMutex.Lock()
+		c.voice = channel
+		c.voiceMutex.Unlock()
+
+		if c.state.Config().LogLevel == slog.LevelDebug {
 			channel.LogLevel = discordgo.LogDebug
 		}
 
@@ -206,27 +613,22 @@ func (c *Conn) Play(guildID string, voiceChannelID string) {
 
 		channel.Speaking(true)
 
-		// Continuously update the bot's presence to reflect the currently playing
-		// song
-		songs := make(chan string)
-		go func() {
-			for song := range songs {
-				err := c.discord.UpdateStatusComplex(discordgo.UpdateStatusData{
-					Activities: []*discordgo.Activity{
-						{
-							Name: song,
-							Type: discordgo.ActivityTypeListening,
-						},
-					},
-				})
-				if err != nil {
-					slog.Error("Failed to set presence", slog.Any("error", err))
-				}
-			}
-		}()
+		events := &announcer{
+			conn:      c,
+			guildID:   guildID,
+			channelID: textChannelID,
+		}
+
+		// Audio is forwarded to whichever voice connection is current, which
+		// lets the connection be replaced if it drops
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opus := make(chan []byte, 2)
+		go c.forwardOpus(ctx, opus)
+		go c.watchVoice(ctx, guildID)
 
 		slog.Debug("Bot is connected to voice channel, starting to play")
-		if err = c.bot.Play(channel.OpusSend, songs); err != nil {
+		if err = c.bot.Play(guildID, opus, events); err != nil {
 			slog.Error("Failed to play", slog.Any("error", err))
 		}
 
@@ -3,6 +3,8 @@ package discord
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/AlexGustafsson/clabbe/internal/state"
 	"github.com/bwmarrin/discordgo"
@@ -13,6 +15,14 @@ type Context struct {
 	context.Context
 	session *discordgo.Session
 	event   *discordgo.InteractionCreate
+	// options holds the options of the invoked (sub)command.
+	options []*discordgo.ApplicationCommandInteractionDataOption
+	// files holds files to attach to the reply.
+	files []*discordgo.File
+	// embeds holds embeds to include in the reply.
+	embeds []*discordgo.MessageEmbed
+	// components holds message components to include in the reply.
+	components []discordgo.MessageComponent
 }
 
 var (
@@ -51,11 +61,30 @@ func (c *Context) VoiceChannel() (string, string, error) {
 	return guild.ID, voiceChannelID, nil
 }
 
+// GuildID returns the id of the guild the command was invoked in.
+func (c *Context) GuildID() string {
+	return c.event.GuildID
+}
+
+// ChannelID returns the id of the channel the command was invoked in.
+func (c *Context) ChannelID() string {
+	return c.event.ChannelID
+}
+
+// GuildName returns the name of the guild the command was invoked in.
+// Returns an empty string if the guild is unknown.
+func (c *Context) GuildName() string {
+	guild, err := c.session.State.Guild(c.event.GuildID)
+	if err != nil {
+		return ""
+	}
+
+	return guild.Name
+}
+
 // String returns a string parameter by key.
 func (c *Context) String(key string) (string, bool) {
-	options := c.event.ApplicationCommandData().Options
-
-	for _, option := range options {
+	for _, option := range c.options {
 		if option.Name == key {
 			return option.StringValue(), true
 		}
@@ -66,9 +95,7 @@ func (c *Context) String(key string) (string, bool) {
 
 // Number returns a number parameter by key.
 func (c *Context) Number(key string) (float64, bool) {
-	options := c.event.ApplicationCommandData().Options
-
-	for _, option := range options {
+	for _, option := range c.options {
 		if option.Name == key {
 			return option.FloatValue(), true
 		}
@@ -79,9 +106,7 @@ func (c *Context) Number(key string) (float64, bool) {
 
 // Boolean returns a boolean parameter by key.
 func (c *Context) Boolean(key string) (bool, bool) {
-	options := c.event.ApplicationCommandData().Options
-
-	for _, option := range options {
+	for _, option := range c.options {
 		if option.Name == key {
 			return option.BoolValue(), true
 		}
@@ -103,3 +128,89 @@ func (c *Context) Entity() state.Entity {
 		Name: name,
 	}
 }
+
+// Attachment returns an attachment parameter by key.
+func (c *Context) Attachment(key string) (*discordgo.MessageAttachment, bool) {
+	resolved := c.event.ApplicationCommandData().Resolved
+	if resolved == nil {
+		return nil, false
+	}
+
+	for _, option := range c.options {
+		if option.Name == key {
+			id, _ := option.Value.(string)
+			attachment, ok := resolved.Attachments[id]
+			return attachment, ok
+		}
+	}
+
+	return nil, false
+}
+
+// TargetMessage returns the message a message command was invoked on.
+func (c *Context) TargetMessage() (*discordgo.Message, bool) {
+	data := c.event.ApplicationCommandData()
+	if data.Resolved == nil {
+		return nil, false
+	}
+
+	message, ok := data.Resolved.Messages[data.TargetID]
+	return message, ok
+}
+
+// AttachFile attaches a file to the reply of the command.
+func (c *Context) AttachFile(name string, contentType string, r io.Reader) {
+	c.files = append(c.files, &discordgo.File{
+		Name:        name,
+		ContentType: contentType,
+		Reader:      r,
+	})
+}
+
+// AddEmbed adds an embed to the reply of the command.
+func (c *Context) AddEmbed(embed *discordgo.MessageEmbed) {
+	c.embeds = append(c.embeds, embed)
+}
+
+// SetComponents sets the message components of the reply of the command.
+func (c *Context) SetComponents(components []discordgo.MessageComponent) {
+	c.components = components
+}
+
+// ResolveRole resolves a role in the guild by mention, id or name.
+func (c *Context) ResolveRole(value string) (string, bool) {
+	guild, err := c.session.State.Guild(c.event.GuildID)
+	if err != nil {
+		return "", false
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(value, "<@&"), ">")
+	for _, role := range guild.Roles {
+		if role.ID == id || strings.EqualFold(role.Name, strings.TrimPrefix(value, "@")) {
+			return role.ID, true
+		}
+	}
+
+	return "", false
+}
+
+// ResolveChannel resolves a text channel in the guild by mention, id or name.
+func (c *Context) ResolveChannel(value string) (string, bool) {
+	guild, err := c.session.State.Guild(c.event.GuildID)
+	if err != nil {
+		return "", false
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(value, "<#"), ">")
+	for _, channel := range guild.Channels {
+		if channel.Type != discordgo.ChannelTypeGuildText {
+			continue
+		}
+
+		if channel.ID == id || strings.EqualFold(channel.Name, strings.TrimPrefix(value, "#")) {
+			return channel.ID, true
+		}
+	}
+
+	return "", false
+}
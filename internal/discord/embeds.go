@@ -0,0 +1,142 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/AlexGustafsson/clabbe/internal/bot"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/timeutil"
+	"github.com/bwmarrin/discordgo"
+)
+
+// commandComponentPrefix is the prefix of the custom id of components that
+// invoke a command when used.
+const commandComponentPrefix = "command:"
+
+// entryEmbed returns an embed describing the entry.
+func entryEmbed(entry state.PlaylistEntry) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:  entry.Title,
+		Fields: make([]*discordgo.MessageEmbedField, 0),
+	}
+
+	if url, err := state.EntryURL(entry); err == nil {
+		embed.URL = url
+	}
+
+	if entry.Source == state.SourceYouTube {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.URI),
+		}
+	}
+
+	requester := entry.AddedBy.Name
+	if entry.AddedBy.Role == state.RoleSystem {
+		requester = "Clabbe"
+	}
+	if requester != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Requested by",
+			Value:  requester,
+			Inline: true,
+		})
+	}
+
+	if entry.Duration > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Duration",
+			Value:  timeutil.FormatDuration(entry.Duration),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
+
+// queuedEmbed returns an embed describing an entry that was queued at the
+// specified position.
+func queuedEmbed(entry state.PlaylistEntry, position int) *discordgo.MessageEmbed {
+	embed := entryEmbed(entry)
+	embed.Author = &discordgo.MessageEmbedAuthor{
+		Name: "Queued",
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   "Position",
+		Value:  fmt.Sprintf("%d", position),
+		Inline: true,
+	})
+	return embed
+}
+
+// nowPlayingEmbed returns an embed describing the currently playing entry.
+func nowPlayingEmbed(entry state.PlaylistEntry, b *bot.Bot) *discordgo.MessageEmbed {
+	embed := entryEmbed(entry)
+	embed.Author = &discordgo.MessageEmbedAuthor{
+		Name: "Now playing",
+	}
+	if b.Paused() {
+		embed.Author.Name = "Paused"
+	}
+
+	if title := b.StreamTitle(); title != "" {
+		embed.Description = fmt.Sprintf("On air: **%s**", title)
+	}
+
+	position := timeutil.FormatDuration(b.Position())
+	if entry.Duration > 0 {
+		position = fmt.Sprintf("%s / %s", position, timeutil.FormatDuration(entry.Duration))
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   "Position",
+		Value:  position,
+		Inline: true,
+	})
+
+	return embed
+}
+
+// playerComponents returns buttons used to control playback.
+func playerComponents(b *bot.Bot) []discordgo.MessageComponent {
+	pause := discordgo.Button{
+		Label:    "Pause",
+		Style:    discordgo.SecondaryButton,
+		CustomID: commandComponentPrefix + "pause",
+	}
+	if b.Paused() {
+		pause.Label = "Resume"
+		pause.Style = discordgo.PrimaryButton
+	}
+
+	repeat := discordgo.Button{
+		Label:    "Repeat",
+		Style:    discordgo.SecondaryButton,
+		CustomID: commandComponentPrefix + "repeat",
+	}
+	if b.Repeat() {
+		repeat.Style = discordgo.PrimaryButton
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				pause,
+				discordgo.Button{
+					Label:    "Skip",
+					Style:    discordgo.SecondaryButton,
+					CustomID: commandComponentPrefix + "skip",
+				},
+				repeat,
+				discordgo.Button{
+					Label:    "Shuffle",
+					Style:    discordgo.SecondaryButton,
+					CustomID: commandComponentPrefix + "shuffle",
+				},
+				discordgo.Button{
+					Label:    "Like",
+					Style:    discordgo.SuccessButton,
+					CustomID: commandComponentPrefix + "like",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,138 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/AlexGustafsson/clabbe/internal/bot"
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
+	"github.com/bwmarrin/discordgo"
+)
+
+var _ bot.EventSink = (*announcer)(nil)
+
+// announcer implements bot.EventSink by announcing events in a text channel.
+type announcer struct {
+	conn    *Conn
+	guildID string
+	// channelID is the id of the text channel playback was started from. Used
+	// unless the guild has configured an announce channel.
+	channelID string
+}
+
+// TrackStarted implements bot.EventSink.
+func (a *announcer) TrackStarted(entry state.PlaylistEntry) {
+	a.conn.skipVotes.Reset()
+
+	a.setPresence(entry.Title)
+
+	a.send(&discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{nowPlayingEmbed(entry, a.conn.bot)},
+		Components: playerComponents(a.conn.bot),
+	})
+}
+
+// TrackFinished implements bot.EventSink.
+func (a *announcer) TrackFinished(entry state.PlaylistEntry) {
+	slog.Debug("Finished playing", slog.String("title", entry.Title))
+}
+
+// TrackFailed implements bot.EventSink.
+func (a *announcer) TrackFailed(entry state.PlaylistEntry, err error) {
+	if errors.Is(err, ytdlp.ErrRateLimited) {
+		a.send(&discordgo.MessageSend{
+			Content: fmt.Sprintf("I couldn't play **%s**, I'm being rate limited. I'll stop for now, try again later using /play", entry.Title),
+		})
+		return
+	}
+
+	reason := "something went wrong"
+	switch {
+	case errors.Is(err, bot.ErrUnsupportedAudioCodec), errors.Is(err, ytdlp.ErrNoFormat):
+		reason = "its audio format isn't supported"
+	case errors.Is(err, bot.ErrUnsupportedSource):
+		reason = "its source isn't available"
+	case errors.Is(err, source.ErrNotFound):
+		reason = "it no longer exists"
+	case errors.Is(err, ytdlp.ErrPrivate):
+		reason = "it's private"
+	case errors.Is(err, ytdlp.ErrAgeRestricted):
+		reason = "it's age restricted"
+	case errors.Is(err, ytdlp.ErrGeoBlocked):
+		reason = "it's not available in my region"
+	case errors.Is(err, ytdlp.ErrUnavailable):
+		reason = "it's no longer available"
+	}
+
+	a.send(&discordgo.MessageSend{
+		Content: fmt.Sprintf("I couldn't play **%s**, %s. Skipping", entry.Title, reason),
+	})
+}
+
+// QueueEmpty implements bot.EventSink.
+func (a *announcer) QueueEmpty() {
+	a.send(&discordgo.MessageSend{
+		Content: "The queue is empty. Queue more songs using /queue",
+	})
+}
+
+// ExtrapolationAdded implements bot.EventSink.
+func (a *announcer) ExtrapolationAdded(entries []state.PlaylistEntry) {
+	var content strings.Builder
+	content.WriteString("The queue ran dry, so I've added these songs.\n")
+	for i, entry := range entries {
+		fmt.Fprintf(&content, "%d. **%s**\n", i+1, entry.Title)
+	}
+
+	for _, chunk := range splitMessage(content.String(), maxMessageLength) {
+		a.send(&discordgo.MessageSend{
+			Content: chunk,
+		})
+	}
+}
+
+// StreamTitleChanged implements bot.EventSink. Only the presence is updated,
+// as stations change songs too often to announce them.
+func (a *announcer) StreamTitleChanged(entry state.PlaylistEntry, title string) {
+	if title == "" {
+		a.setPresence(entry.Title)
+	} else {
+		a.setPresence(fmt.Sprintf("%s on %s", title, entry.Title))
+	}
+}
+
+// setPresence shows the bot as listening to name.
+func (a *announcer) setPresence(name string) {
+	err := a.conn.discord.UpdateStatusComplex(discordgo.UpdateStatusData{
+		Activities: []*discordgo.Activity{
+			{
+				Name: name,
+				Type: discordgo.ActivityTypeListening,
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to set presence", slog.Any("error", err))
+	}
+}
+
+// send sends a message to the guild's announce channel, or the channel
+// playback was started from.
+func (a *announcer) send(message *discordgo.MessageSend) {
+	channelID := a.conn.state.Guilds.Settings(a.guildID).AnnounceChannel
+	if channelID == "" {
+		channelID = a.channelID
+	}
+
+	if channelID == "" {
+		return
+	}
+
+	if _, err := a.conn.discord.ChannelMessageSendComplex(channelID, message); err != nil {
+		slog.Error("Failed to send announcement", slog.String("channelId", channelID), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,39 @@
+package discord
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/bwmarrin/discordgo"
+)
+
+// urlRegex matches URLs in text. Discord allows URLs to be wrapped in angle
+// brackets to suppress embeds and Markdown links wrap URLs in parentheses.
+var urlRegex = regexp.MustCompile(`https?://[^\s<>()\[\]]+`)
+
+// messageVideoIDs returns the ids of the YouTube videos linked in the
+// message's content and embeds, in order of appearance.
+func messageVideoIDs(message *discordgo.Message) []string {
+	texts := []string{message.Content}
+	for _, embed := range message.Embeds {
+		texts = append(texts, embed.URL, embed.Description)
+		if embed.Video != nil {
+			texts = append(texts, embed.Video.URL)
+		}
+	}
+
+	ids := make([]string, 0)
+	for _, text := range texts {
+		for _, url := range urlRegex.FindAllString(text, -1) {
+			source, id, ok := state.ParseEntryURL(url)
+			if !ok || source != state.SourceYouTube || slices.Contains(ids, id) {
+				continue
+			}
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
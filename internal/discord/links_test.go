@@ -0,0 +1,24 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageVideoIDs(t *testing.T) {
+	message := &discordgo.Message{
+		Content: "listen to this https://youtu.be/dQw4w9WgXcQ and <https://www.youtube.com/watch?v=BaW_jenozKc>, or [this](https://example.com/song.mp3)",
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			},
+			{
+				URL: "https://music.youtube.com/watch?v=jNQXAC9IVRw",
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"dQw4w9WgXcQ", "BaW_jenozKc", "jNQXAC9IVRw"}, messageVideoIDs(message))
+}
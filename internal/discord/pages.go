@@ -0,0 +1,199 @@
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/bwmarrin/discordgo"
+)
+
+// pageComponentPrefix is the prefix of the custom id of pagination
+// components. The full id is "page:<view>:<page>", which lets any message be
+// paginated without keeping track of it.
+const pageComponentPrefix = "page:"
+
+// pageSize is the number of entries shown on each page.
+const pageSize = 10
+
+// maxMessageLength is the maximum length of a Discord message.
+const maxMessageLength = 2000
+
+// playlistView is a paginated view of a playlist.
+type playlistView struct {
+	playlist func(*state.State) *state.Playlist
+	format   string
+	reversed bool
+}
+
+// playlistViews holds the paginated views, keyed by the name of the command
+// that shows them.
+var playlistViews = map[string]playlistView{
+	"queued": {
+		playlist: func(s *state.State) *state.Playlist { return s.Queue },
+		format:   "{{.Index}}. {{.EntityName}} queued {{.RelativeTime}} - **{{.Title}}**\n",
+	},
+	"suggestions": {
+		playlist: func(s *state.State) *state.Playlist { return s.Suggestions },
+		format:   "{{.Index}}. **{{.Title}}**\n",
+	},
+	"recent": {
+		playlist: func(s *state.State) *state.Playlist { return s.History },
+		format:   "{{.Index}}. {{.EntityName}} played {{.RelativeTime}} - **{{.Title}}**\n",
+		reversed: true,
+	},
+}
+
+// renderPage renders a page of the named view. The page is clamped to the
+// pages available. Returns the contents and the pagination components, if
+// there is more than one page.
+func renderPage(s *state.State, name string, page int) (string, []discordgo.MessageComponent, error) {
+	view, ok := playlistViews[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown view: %s", name)
+	}
+
+	playlist := view.playlist(s)
+	pages := max((playlist.Len()+pageSize-1)/pageSize, 1)
+	page = min(max(page, 0), pages-1)
+
+	contents, err := playlist.FormatPage(view.format, page*pageSize, pageSize, view.reversed)
+	if err != nil {
+		return "", nil, err
+	}
+	if contents == "" {
+		return "No songs", nil, nil
+	}
+
+	if pages == 1 {
+		return fitLines(contents, maxMessageLength), nil, nil
+	}
+
+	footer := fmt.Sprintf("\nPage %d of %d", page+1, pages)
+	return fitLines(contents, maxMessageLength-len(footer)) + footer, paginationComponents(name, page, pages), nil
+}
+
+// fitLines truncates the lines of content so that it's no longer than limit
+// bytes, giving each line an equal share. Lines ending in bold text, such as
+// titles, keep their formatting.
+func fitLines(content string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	share := limit / len(lines)
+
+	var builder strings.Builder
+	for _, line := range lines {
+		if len(line) <= share {
+			builder.WriteString(line)
+			continue
+		}
+
+		line, newline := strings.CutSuffix(line, "\n")
+		line, bold := strings.CutSuffix(line, "**")
+
+		suffix := "…"
+		if bold {
+			suffix += "**"
+		}
+		if newline {
+			suffix += "\n"
+		}
+
+		i := max(share-len(suffix), 0)
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		builder.WriteString(line[:i] + suffix)
+	}
+
+	return builder.String()
+}
+
+// paginationComponents returns buttons used to navigate between pages.
+func paginationComponents(name string, page int, pages int) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: pageToken(name, page-1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next",
+					Style:    discordgo.SecondaryButton,
+					CustomID: pageToken(name, page+1),
+					Disabled: page >= pages-1,
+				},
+			},
+		},
+	}
+}
+
+// pageToken returns the custom id of a component that shows the page of the
+// named view.
+func pageToken(name string, page int) string {
+	return fmt.Sprintf("%s%s:%d", pageComponentPrefix, name, page)
+}
+
+// parsePageToken parses a custom id created by pageToken.
+func parsePageToken(customID string) (string, int, bool) {
+	token, ok := strings.CutPrefix(customID, pageComponentPrefix)
+	if !ok {
+		return "", 0, false
+	}
+
+	name, value, ok := strings.Cut(token, ":")
+	if !ok {
+		return "", 0, false
+	}
+
+	page, err := strconv.Atoi(value)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return name, page, true
+}
+
+// splitMessage splits content into chunks no longer than limit bytes. Content
+// is split on line breaks where possible.
+func splitMessage(content string, limit int) []string {
+	chunks := make([]string, 0)
+
+	var builder strings.Builder
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if builder.Len()+len(line) > limit && builder.Len() > 0 {
+			chunks = append(chunks, builder.String())
+			builder.Reset()
+		}
+
+		// Lines that don't fit a message on their own are split on rune
+		// boundaries
+		for len(line) > limit {
+			i := limit
+			for i > 0 && !utf8.RuneStart(line[i]) {
+				i--
+			}
+			chunks = append(chunks, line[:i])
+			line = line[i:]
+		}
+
+		builder.WriteString(line)
+	}
+
+	if builder.Len() > 0 || len(chunks) == 0 {
+		chunks = append(chunks, builder.String())
+	}
+
+	return chunks
+}
@@ -0,0 +1,97 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageToken(t *testing.T) {
+	name, page, ok := parsePageToken(pageToken("queued", 3))
+	require.True(t, ok)
+	assert.Equal(t, "queued", name)
+	assert.Equal(t, 3, page)
+
+	_, _, ok = parsePageToken("command:skip")
+	assert.False(t, ok)
+
+	_, _, ok = parsePageToken("page:queued:next")
+	assert.False(t, ok)
+}
+
+func TestSplitMessage(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Content  string
+		Limit    int
+		Expected []string
+	}{
+		{
+			Name:     "empty",
+			Content:  "",
+			Limit:    10,
+			Expected: []string{""},
+		},
+		{
+			Name:     "fits",
+			Content:  "abc\ndef\n",
+			Limit:    10,
+			Expected: []string{"abc\ndef\n"},
+		},
+		{
+			Name:     "lines",
+			Content:  "abcd\nefgh\nijkl\n",
+			Limit:    10,
+			Expected: []string{"abcd\nefgh\n", "ijkl\n"},
+		},
+		{
+			Name:     "long line",
+			Content:  "abcdefghijkl\nmn",
+			Limit:    5,
+			Expected: []string{"abcde", "fghij", "kl\nmn"},
+		},
+		{
+			Name:     "runes",
+			Content:  "ååå",
+			Limit:    3,
+			Expected: []string{"å", "å", "å"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			actual := splitMessage(testCase.Content, testCase.Limit)
+			assert.Equal(t, testCase.Expected, actual)
+			assert.Equal(t, testCase.Content, strings.Join(actual, ""))
+		})
+	}
+}
+
+func TestFitLines(t *testing.T) {
+	// Content that fits is left as-is
+	assert.Equal(t, "1. **Foo**\n2. **Bar**\n", fitLines("1. **Foo**\n2. **Bar**\n", 100))
+
+	long := strings.Repeat("ä", 20)
+	content := "1. **" + long + "**\n2. **Bar**\n"
+	fitted := fitLines(content, 30)
+	assert.LessOrEqual(t, len(fitted), 30)
+	assert.True(t, utf8.ValidString(fitted))
+	assert.Equal(t, "1. **ää…**\n2. **Bar**\n", fitted)
+}
+
+func TestRenderPageFits(t *testing.T) {
+	s := &state.State{Queue: state.NewPlaylist()}
+	for range 25 {
+		s.Queue.Push(state.PlaylistEntry{Title: strings.Repeat("🎵", 100)})
+	}
+
+	contents, components, err := renderPage(s, "queued", 1)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(contents), maxMessageLength)
+	assert.True(t, strings.HasSuffix(contents, "\nPage 2 of 3"))
+	assert.NotEmpty(t, components)
+}
@@ -0,0 +1,151 @@
+package discord
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/bwmarrin/discordgo"
+)
+
+// permissionNames holds human-readable names of permissions used by commands.
+var permissionNames = map[int64]string{
+	discordgo.PermissionAdministrator:    "Administrator",
+	discordgo.PermissionManageServer:     "Manage Server",
+	discordgo.PermissionManageChannels:   "Manage Channels",
+	discordgo.PermissionVoiceMoveMembers: "Move Members",
+}
+
+// defaultDJCommands returns the names of the registered commands which
+// require the DJ role by default.
+func (c *Conn) defaultDJCommands() []string {
+	names := make([]string, 0)
+	var walk func(prefix string, commands []Command)
+	walk = func(prefix string, commands []Command) {
+		for _, command := range commands {
+			name := strings.TrimSpace(prefix + " " + command.Name)
+			if command.DJ {
+				names = append(names, name)
+			}
+			walk(name, command.Subcommands)
+		}
+	}
+	for _, command := range c.commands {
+		walk("", []Command{command})
+	}
+	slices.Sort(names)
+	return names
+}
+
+// commandExists returns whether or not a registered command with the full
+// name exists.
+func (c *Conn) commandExists(name string) bool {
+	parts := strings.Fields(name)
+	if len(parts) == 0 {
+		return false
+	}
+
+	command, ok := c.commands[parts[0]]
+	if !ok {
+		return false
+	}
+
+	for _, part := range parts[1:] {
+		found := false
+		for _, subcommand := range command.Subcommands {
+			if subcommand.Name == part {
+				command = subcommand
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// authorize checks whether or not the member invoking a command is allowed to
+// do so. The name is the full name of the invoked command, including the names
+// of parent commands. Returns a message explaining why the invocation was
+// denied, if it was.
+func (c *Conn) authorize(event *discordgo.InteractionCreate, name string, command Command, permissions int64) (string, bool) {
+	// Commands are only registered for guilds
+	if event.Member == nil {
+		return "Commands can only be used in servers", false
+	}
+
+	// Discord hides commands from members without the required permissions,
+	// but server admins may override that. Make sure the permissions are held
+	if event.Member.Permissions&permissions != permissions {
+		missing := make([]string, 0)
+		for permission, name := range permissionNames {
+			if permissions&permission != 0 && event.Member.Permissions&permission == 0 {
+				missing = append(missing, "**"+name+"**")
+			}
+		}
+		if len(missing) == 0 {
+			return "You don't have permission to do that", false
+		}
+		slices.Sort(missing)
+		return fmt.Sprintf("You need the %s permission to do that", strings.Join(missing, " and ")), false
+	}
+
+	settings := c.state.Guilds.Settings(event.GuildID)
+	djCommands := settings.DJCommands
+	if djCommands == nil {
+		djCommands = c.defaultDJCommands()
+	}
+
+	if !slices.Contains(djCommands, name) || c.isDJ(event.GuildID, event.Member) {
+		return "", true
+	}
+
+	if command.AllowRequester && settings.AllowsRequesterSkip() {
+		current := c.bot.NowPlaying()
+		if current != nil && current.AddedBy.ID == fmt.Sprintf("%s/%s", event.GuildID, event.Member.User.ID) {
+			return "", true
+		}
+
+		return fmt.Sprintf("Only members with the <@&%s> role or the person who requested the current song can do that", settings.DJRole), false
+	}
+
+	return fmt.Sprintf("Only members with the <@&%s> role can do that", settings.DJRole), false
+}
+
+// canSkipWithoutVote returns whether or not the member may skip the current
+// entry without a vote. DJs may, as long as the guild has a DJ role. Without
+// one every member is a DJ, which would make voting pointless. The member who
+// requested the entry may, if the guild allows it.
+func (c *Conn) canSkipWithoutVote(guildID string, member *discordgo.Member, entity state.Entity, current *state.PlaylistEntry) bool {
+	settings := c.state.Guilds.Settings(guildID)
+
+	if settings.DJRole != "" && c.isDJ(guildID, member) {
+		return true
+	}
+
+	return settings.AllowsRequesterSkip() && current.AddedBy.ID == entity.ID
+}
+
+// isDJ returns whether or not the member is considered a DJ in the guild.
+// If the guild has no DJ role, every member is a DJ. Members that are allowed
+// to manage the server are always DJs.
+func (c *Conn) isDJ(guildID string, member *discordgo.Member) bool {
+	settings := c.state.Guilds.Settings(guildID)
+	if settings.DJRole == "" {
+		return true
+	}
+
+	if member == nil {
+		return false
+	}
+
+	if member.Permissions&discordgo.PermissionManageServer != 0 {
+		return true
+	}
+
+	return slices.Contains(member.Roles, settings.DJRole)
+}
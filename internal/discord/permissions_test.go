@@ -0,0 +1,131 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/AlexGustafsson/clabbe/internal/bot"
+	"github.com/AlexGustafsson/clabbe/internal/source"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestConn returns a connection with a few commands, not connected to
+// Discord.
+func newTestConn() *Conn {
+	s := &state.State{Guilds: state.NewGuilds()}
+	s.SetConfig(state.DefaultConfig())
+
+	return &Conn{
+		state: s,
+		bot:   bot.New(s, nil, source.NewRegistry()),
+		commands: map[string]Command{
+			"queue": {Name: "queue"},
+			"stop":  {Name: "stop", DJ: true},
+			"skip":  {Name: "skip", DJ: true, AllowRequester: true},
+			"settings": {Name: "settings", Permissions: discordgo.PermissionManageServer, Subcommands: []Command{
+				{Name: "get"},
+				{Name: "set", DJ: true},
+			}},
+		},
+	}
+}
+
+func newTestInteraction(member *discordgo.Member) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			GuildID: "1",
+			Member:  member,
+		},
+	}
+}
+
+func TestDefaultDJCommands(t *testing.T) {
+	conn := newTestConn()
+	assert.Equal(t, []string{"settings set", "skip", "stop"}, conn.defaultDJCommands())
+}
+
+func TestIsDJ(t *testing.T) {
+	conn := newTestConn()
+	member := &discordgo.Member{User: &discordgo.User{ID: "2"}}
+	admin := &discordgo.Member{User: &discordgo.User{ID: "3"}, Permissions: discordgo.PermissionManageServer}
+	dj := &discordgo.Member{User: &discordgo.User{ID: "4"}, Roles: []string{"5"}}
+
+	// Everyone is a DJ without a DJ role
+	assert.True(t, conn.isDJ("1", member))
+
+	conn.state.Guilds.SetSettings("1", state.GuildSettings{DJRole: "5"})
+	assert.False(t, conn.isDJ("1", member))
+	assert.False(t, conn.isDJ("1", nil))
+	assert.True(t, conn.isDJ("1", admin))
+	assert.True(t, conn.isDJ("1", dj))
+}
+
+func TestAuthorize(t *testing.T) {
+	conn := newTestConn()
+	member := &discordgo.Member{User: &discordgo.User{ID: "2"}}
+	dj := &discordgo.Member{User: &discordgo.User{ID: "4"}, Roles: []string{"5"}}
+
+	// Commands can't be used outside of guilds
+	_, ok := conn.authorize(newTestInteraction(nil), "queue", conn.commands["queue"], 0)
+	assert.False(t, ok)
+
+	// Permissions are required even if Discord shows the command
+	message, ok := conn.authorize(newTestInteraction(member), "settings get", conn.commands["settings"].Subcommands[0], discordgo.PermissionManageServer)
+	assert.False(t, ok)
+	assert.Equal(t, "You need the **Manage Server** permission to do that", message)
+
+	// Everyone may use DJ commands without a DJ role
+	_, ok = conn.authorize(newTestInteraction(member), "stop", conn.commands["stop"], 0)
+	assert.True(t, ok)
+
+	conn.state.Guilds.SetSettings("1", state.GuildSettings{DJRole: "5"})
+
+	_, ok = conn.authorize(newTestInteraction(member), "queue", conn.commands["queue"], 0)
+	assert.True(t, ok)
+
+	message, ok = conn.authorize(newTestInteraction(member), "stop", conn.commands["stop"], 0)
+	assert.False(t, ok)
+	assert.Equal(t, "Only members with the <@&5> role can do that", message)
+
+	_, ok = conn.authorize(newTestInteraction(dj), "stop", conn.commands["stop"], 0)
+	assert.True(t, ok)
+
+	// Members who didn't request the current song may not skip it
+	message, ok = conn.authorize(newTestInteraction(member), "skip", conn.commands["skip"], 0)
+	assert.False(t, ok)
+	assert.Equal(t, "Only members with the <@&5> role or the person who requested the current song can do that", message)
+
+	// Guilds may choose which commands require the role
+	conn.state.Guilds.SetSettings("1", state.GuildSettings{DJRole: "5", DJCommands: []string{}})
+	_, ok = conn.authorize(newTestInteraction(member), "stop", conn.commands["stop"], 0)
+	assert.True(t, ok)
+
+	conn.state.Guilds.SetSettings("1", state.GuildSettings{DJRole: "5", DJCommands: []string{"queue"}})
+	_, ok = conn.authorize(newTestInteraction(member), "queue", conn.commands["queue"], 0)
+	assert.False(t, ok)
+}
+
+func TestCanSkipWithoutVote(t *testing.T) {
+	conn := newTestConn()
+	member := &discordgo.Member{User: &discordgo.User{ID: "2"}}
+	dj := &discordgo.Member{User: &discordgo.User{ID: "4"}, Roles: []string{"5"}}
+	requester := state.Entity{ID: "1/2"}
+	other := state.Entity{ID: "1/4"}
+	current := &state.PlaylistEntry{Title: "Song", AddedBy: requester}
+
+	// Without a DJ role everyone is a DJ, but must still vote
+	assert.False(t, conn.canSkipWithoutVote("1", dj, other, current))
+	assert.True(t, conn.canSkipWithoutVote("1", member, requester, current))
+
+	conn.state.Guilds.SetSettings("1", state.GuildSettings{DJRole: "5"})
+	assert.True(t, conn.canSkipWithoutVote("1", dj, other, current))
+	assert.False(t, conn.canSkipWithoutVote("1", member, other, current))
+	assert.True(t, conn.canSkipWithoutVote("1", member, requester, current))
+
+	// Guilds may require requesters to vote too
+	disallowed := false
+	conn.state.Guilds.SetSettings("1", state.GuildSettings{DJRole: "5", RequesterSkip: &disallowed})
+	assert.False(t, conn.canSkipWithoutVote("1", member, requester, current))
+	assert.True(t, conn.canSkipWithoutVote("1", dj, other, current))
+}
@@ -0,0 +1,160 @@
+package discord
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// voiceReconnectGrace is the time to wait for discordgo to recover a voice
+	// connection on its own before reconnecting.
+	voiceReconnectGrace = 5 * time.Second
+	// maxVoiceReconnectAttempts is the number of times to try to reconnect
+	// before giving up.
+	maxVoiceReconnectAttempts = 8
+	// maxVoiceReconnectBackoff is the longest time to wait between attempts.
+	maxVoiceReconnectBackoff = time.Minute
+)
+
+// forwardOpus forwards OPUS frames to the current voice connection until ctx
+// is done. While the connection isn't ready, frames are held back, which
+// blocks playback.
+func (c *Conn) forwardOpus(ctx context.Context, opus <-chan []byte) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame := <-opus:
+			if !c.sendOpus(ctx, frame) {
+				return
+			}
+		}
+	}
+}
+
+// sendOpus sends a frame to the current voice connection, waiting for it to
+// become ready. Returns false if ctx is done before the frame is sent.
+func (c *Conn) sendOpus(ctx context.Context, frame []byte) bool {
+	for {
+		c.voiceMutex.Lock()
+		voice := c.voice
+		c.voiceMutex.Unlock()
+
+		if voice != nil && voiceReady(voice) {
+			select {
+			case voice.OpusSend <- frame:
+				return true
+			case <-ctx.Done():
+				return false
+			case <-time.After(100 * time.Millisecond):
+				// The connection may have dropped or been replaced, try again
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// watchVoice watches the current voice connection until ctx is done,
+// reconnecting if it drops.
+func (c *Conn) watchVoice(ctx context.Context, guildID string) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var droppedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.voiceMutex.Lock()
+		voice := c.voice
+		c.voiceMutex.Unlock()
+
+		if voice == nil || voiceReady(voice) {
+			droppedAt = time.Time{}
+			continue
+		}
+
+		if droppedAt.IsZero() {
+			droppedAt = time.Now()
+			continue
+		}
+
+		if time.Since(droppedAt) >= voiceReconnectGrace {
+			c.reconnectVoice(ctx, guildID)
+			droppedAt = time.Time{}
+		}
+	}
+}
+
+// reconnectVoice rejoins the voice channel with backoff. Playback is paused
+// while reconnecting and the current song is restarted at its last position
+// once reconnected. If reconnecting fails, the bot leaves, keeping the queue.
+func (c *Conn) reconnectVoice(ctx context.Context, guildID string) {
+	slog.Warn("Voice connection dropped, reconnecting")
+
+	// Don't resume playback paused by others
+	paused := false
+	if !c.bot.Paused() {
+		c.bot.Pause()
+		paused = true
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= maxVoiceReconnectAttempts; attempt++ {
+		voiceChannelID, ok := c.VoiceChannelID()
+		if !ok {
+			return
+		}
+
+		voice, err := c.discord.ChannelVoiceJoin(guildID, voiceChannelID, false, true)
+		if err == nil {
+			slog.Info("Reconnected to voice channel", slog.Int("attempt", attempt))
+			c.state.Metrics.VoiceReconnects.WithLabelValues("success").Inc()
+
+			c.voiceMutex.Lock()
+			c.voice = voice
+			c.voiceMutex.Unlock()
+			voice.Speaking(true)
+
+			// The stream has likely timed out while waiting
+			c.bot.Restart()
+			if paused {
+				c.bot.Resume()
+			}
+			return
+		}
+
+		slog.Warn("Failed to reconnect to voice channel", slog.Int("attempt", attempt), slog.Any("error", err))
+		c.state.Metrics.VoiceReconnects.WithLabelValues("failure").Inc()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxVoiceReconnectBackoff)
+	}
+
+	slog.Error("Failed to reconnect to voice channel, giving up")
+	c.bot.Leave()
+}
+
+// voiceReady returns whether or not the voice connection is ready to send
+// audio.
+func voiceReady(voice *discordgo.VoiceConnection) bool {
+	voice.RLock()
+	defer voice.RUnlock()
+
+	return voice.Ready
+}
@@ -0,0 +1,123 @@
+package discord
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// idleChannel describes a channel the bot left due to inactivity.
+type idleChannel struct {
+	guildID        string
+	voiceChannelID string
+	textChannelID  string
+}
+
+// Move moves the bot to another voice channel in the guild it's playing in.
+func (c *Conn) Move(voiceChannelID string) error {
+	c.voiceMutex.Lock()
+	voice := c.voice
+	c.voiceMutex.Unlock()
+
+	if voice == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	slog.Debug("Moving bot to voice channel", slog.String("voiceChannelID", voiceChannelID))
+	if err := voice.ChangeChannel(voiceChannelID, false, true); err != nil {
+		return err
+	}
+
+	c.voiceMutex.Lock()
+	c.voiceChannelID = voiceChannelID
+	c.voiceMutex.Unlock()
+
+	c.updateIdle()
+	return nil
+}
+
+// handleVoiceStateUpdate handles users joining, leaving and moving between
+// voice channels.
+func (c *Conn) handleVoiceStateUpdate(session *discordgo.Session, event *discordgo.VoiceStateUpdate) {
+	c.voiceMutex.Lock()
+	if event.UserID == session.State.User.ID {
+		// The bot may be moved by others
+		if c.isConnected && event.GuildID == c.guildID && event.ChannelID != "" {
+			c.voiceChannelID = event.ChannelID
+		}
+	} else if idle := c.idle; !c.isConnected && idle != nil && event.GuildID == idle.guildID && event.ChannelID == idle.voiceChannelID {
+		// Resume playback once someone joins the channel the bot left
+		if (event.Member == nil || event.Member.User == nil || !event.Member.User.Bot) && c.state.Queue.Len() > 0 {
+			c.voiceMutex.Unlock()
+			slog.Debug("User rejoined idle channel, resuming playback", slog.String("voiceChannelID", idle.voiceChannelID))
+			c.Play(idle.guildID, idle.voiceChannelID, idle.textChannelID)
+			return
+		}
+	}
+	c.voiceMutex.Unlock()
+
+	c.updateIdle()
+}
+
+// updateIdle pauses playback when there's nobody listening and resumes it once
+// someone returns. If nobody returns within the configured idle timeout, the
+// bot leaves.
+func (c *Conn) updateIdle() {
+	c.voiceMutex.Lock()
+	defer c.voiceMutex.Unlock()
+
+	if !c.isConnected {
+		return
+	}
+
+	if len(c.listeners(c.guildID, c.voiceChannelID)) > 0 {
+		if c.idleTimer != nil {
+			c.idleTimer.Stop()
+			c.idleTimer = nil
+		}
+
+		if c.pausedWhileAlone {
+			slog.Debug("Listeners joined, resuming playback")
+			c.pausedWhileAlone = false
+			c.bot.Resume()
+		}
+		return
+	}
+
+	// Don't resume playback paused by users
+	if !c.pausedWhileAlone && !c.bot.Paused() {
+		slog.Debug("No listeners left, pausing playback")
+		c.pausedWhileAlone = true
+		c.bot.Pause()
+	}
+
+	timeout := c.state.Config().IdleTimeout
+	if c.idleTimer == nil && timeout > 0 {
+		c.idleTimer = time.AfterFunc(timeout, c.leaveIdle)
+	}
+}
+
+// leaveIdle leaves the voice channel due to inactivity. The current song is
+// put back in the queue and resumed once someone joins the channel again.
+func (c *Conn) leaveIdle() {
+	c.voiceMutex.Lock()
+	if !c.isConnected || c.idleTimer == nil {
+		c.voiceMutex.Unlock()
+		return
+	}
+
+	slog.Debug("Leaving voice channel due to inactivity", slog.String("voiceChannelID", c.voiceChannelID))
+	c.idleTimer = nil
+	c.pausedWhileAlone = false
+	// Remembered as idle once disconnected
+	c.leaving = &idleChannel{
+		guildID:        c.guildID,
+		voiceChannelID: c.voiceChannelID,
+		textChannelID:  c.textChannelID,
+	}
+	c.voiceMutex.Unlock()
+
+	c.bot.Leave()
+}
@@ -0,0 +1,47 @@
+package discord
+
+import (
+	"math"
+	"sync"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+)
+
+// skipVotes tracks votes to skip the currently playing song.
+type skipVotes struct {
+	mutex sync.Mutex
+	// entry is the entry being voted on, as returned by bot.NowPlaying.
+	entry  *state.PlaylistEntry
+	voters map[string]struct{}
+}
+
+// Vote registers a vote by the user to skip the entry. Returns the current
+// number of votes. Voting multiple times has no effect. Votes for previous
+// entries are discarded.
+func (v *skipVotes) Vote(entry *state.PlaylistEntry, userID string) int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if v.voters == nil || v.entry != entry {
+		v.entry = entry
+		v.voters = make(map[string]struct{})
+	}
+
+	v.voters[userID] = struct{}{}
+	return len(v.voters)
+}
+
+// Reset removes all votes.
+func (v *skipVotes) Reset() {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.entry = nil
+	v.voters = nil
+}
+
+// requiredVotes returns the number of votes required to skip a song given the
+// number of listeners and the fraction of listeners that need to vote.
+func requiredVotes(listeners int, fraction float64) int {
+	return max(1, int(math.Ceil(float64(listeners)*fraction)))
+}
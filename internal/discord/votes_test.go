@@ -0,0 +1,46 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipVotes(t *testing.T) {
+	first := &state.PlaylistEntry{Title: "First"}
+	second := &state.PlaylistEntry{Title: "Second"}
+
+	var votes skipVotes
+	assert.Equal(t, 1, votes.Vote(first, "a"))
+	assert.Equal(t, 1, votes.Vote(first, "a"))
+	assert.Equal(t, 2, votes.Vote(first, "b"))
+
+	// Votes for a previous entry don't count
+	assert.Equal(t, 1, votes.Vote(second, "c"))
+	assert.Equal(t, 2, votes.Vote(second, "a"))
+
+	votes.Reset()
+	assert.Equal(t, 1, votes.Vote(second, "b"))
+}
+
+func TestSkipVotesThreshold(t *testing.T) {
+	entry := &state.PlaylistEntry{Title: "Song"}
+	listeners := 5
+	required := requiredVotes(listeners, 0.5)
+	assert.Equal(t, 3, required)
+
+	var votes skipVotes
+	assert.Less(t, votes.Vote(entry, "a"), required)
+	assert.Less(t, votes.Vote(entry, "b"), required)
+	assert.Less(t, votes.Vote(entry, "b"), required)
+	assert.GreaterOrEqual(t, votes.Vote(entry, "c"), required)
+}
+
+func TestRequiredVotes(t *testing.T) {
+	assert.Equal(t, 1, requiredVotes(0, 0.5))
+	assert.Equal(t, 1, requiredVotes(1, 0.5))
+	assert.Equal(t, 2, requiredVotes(3, 0.5))
+	assert.Equal(t, 2, requiredVotes(4, 0.5))
+	assert.Equal(t, 4, requiredVotes(4, 1))
+}
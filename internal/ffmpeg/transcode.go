@@ -0,0 +1,73 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+type Error struct {
+	ExitCode int
+	Stderr   string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("ffmpeg: exit code %d", e.ExitCode)
+}
+
+type TranscodeOptions struct {
+	// Volume is the volume in percent.
+	Volume int
+}
+
+// Transcode uses ffmpeg to transcode the audio of input, a path or URL, to
+// stereo opus audio in frames of 20ms, written to w in a webm container.
+// Nil options transcode the audio as-is.
+func Transcode(ctx context.Context, input string, w io.Writer, options *TranscodeOptions) error {
+	return transcode(ctx, input, nil, w, options)
+}
+
+// TranscodeReader is like Transcode, but reads the input from r. Useful for
+// streams which have to be processed before they're transcoded.
+func TranscodeReader(ctx context.Context, r io.Reader, w io.Writer, options *TranscodeOptions) error {
+	return transcode(ctx, "pipe:0", r, w, options)
+}
+
+// transcode transcodes input, reading from stdin if non-nil.
+func transcode(ctx context.Context, input string, stdin io.Reader, w io.Writer, options *TranscodeOptions) error {
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if stdin == nil {
+		args = append(args, "-nostdin")
+	}
+	args = append(args, "-i", input, "-map", "0:a:0")
+
+	if options != nil && options.Volume != 100 {
+		args = append(args, "-filter:a", fmt.Sprintf("volume=%.2f", float64(options.Volume)/100))
+	}
+
+	args = append(args, "-c:a", "libopus", "-b:a", "128k", "-frame_duration", "20", "-ar", "48000", "-ac", "2", "-f", "webm", "-")
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	cmd.Stdin = stdin
+	cmd.Stdout = w
+
+	var buffer bytes.Buffer
+	cmd.Stderr = &buffer
+
+	if err := cmd.Run(); err != nil {
+		// Such as when ffmpeg is not installed
+		if cmd.ProcessState == nil {
+			return err
+		}
+
+		return Error{
+			ExitCode: cmd.ProcessState.ExitCode(),
+			Stderr:   buffer.String(),
+		}
+	}
+
+	return nil
+}
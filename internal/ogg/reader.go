@@ -0,0 +1,172 @@
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// SEE: https://www.rfc-editor.org/rfc/rfc3533
+
+const pageHeaderSize = 27
+
+var capturePattern = []byte("OggS")
+
+// Page is a page of an Ogg stream.
+type Page struct {
+	// Continued is true if the first packet of the page continues a packet of
+	// the previous page.
+	Continued bool
+	// GranulePosition is the codec-specific position of the page, such as the
+	// number of samples decoded once the page's packets are decoded.
+	GranulePosition uint64
+	// Serial identifies the logical stream of the page.
+	Serial uint32
+	// Segments are the segments of the page's payload. A segment shorter than
+	// 255 bytes ends a packet.
+	Segments [][]byte
+}
+
+// Reader reads packets from an Ogg stream. Only streams holding a single
+// logical stream are supported.
+type Reader struct {
+	reader io.Reader
+	// packets holds the packets of the current page yet to be read.
+	packets [][]byte
+	// partial holds a packet continued on the next page.
+	partial []byte
+	// granulePosition is the granule position of the last read page.
+	granulePosition uint64
+}
+
+// NewReader creates a new Reader that will read from reader.
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{
+		reader: reader,
+	}
+}
+
+// ReadPacket reads the next packet. Returns io.EOF once the stream ends.
+func (r *Reader) ReadPacket() ([]byte, error) {
+	for len(r.packets) == 0 {
+		page, err := ReadPage(r.reader)
+		if err == io.EOF && r.partial != nil {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		r.granulePosition = page.GranulePosition
+
+		// Drop packets continued from a page we never saw, such as when starting
+		// to read in the middle of a stream
+		if !page.Continued {
+			r.partial = nil
+		}
+		skip := page.Continued && r.partial == nil
+
+		for _, segment := range page.Segments {
+			if !skip {
+				r.partial = append(r.partial, segment...)
+			}
+
+			if len(segment) < 255 {
+				if !skip {
+					r.packets = append(r.packets, r.partial)
+				}
+				r.partial = nil
+				skip = false
+			}
+		}
+
+		// The page only continued a packet we never saw, which continues on the
+		// next page
+		if skip {
+			r.partial = nil
+		}
+	}
+
+	packet := r.packets[0]
+	r.packets = r.packets[1:]
+	return packet, nil
+}
+
+// GranulePosition returns the granule position of the last read page.
+func (r *Reader) GranulePosition() uint64 {
+	return r.granulePosition
+}
+
+// ReadPage reads the next page.
+func ReadPage(r io.Reader) (*Page, error) {
+	header := make([]byte, pageHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header[0:4], capturePattern) {
+		return nil, fmt.Errorf("ogg: invalid capture pattern")
+	}
+
+	if header[4] != 0 {
+		return nil, fmt.Errorf("ogg: unsupported version %d", header[4])
+	}
+
+	page := &Page{
+		Continued:       header[5]&0x01 != 0,
+		GranulePosition: binary.LittleEndian.Uint64(header[6:14]),
+		Serial:          binary.LittleEndian.Uint32(header[14:18]),
+	}
+
+	lacing := make([]byte, header[26])
+	if _, err := io.ReadFull(r, lacing); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	page.Segments = make([][]byte, len(lacing))
+	for i, size := range lacing {
+		page.Segments[i] = make([]byte, size)
+		if _, err := io.ReadFull(r, page.Segments[i]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+
+	return page, nil
+}
+
+// LastGranulePosition returns the granule position of the last page of an
+// Ogg stream, which is used to determine the duration of the stream.
+func LastGranulePosition(r io.ReadSeeker) (uint64, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	// Pages are at most about 64KiB
+	offset := max(size-65307, 0)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	tail, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the last complete page
+	for i := bytes.LastIndex(tail, capturePattern); i >= 0; i = bytes.LastIndex(tail[:i], capturePattern) {
+		page, err := ReadPage(bytes.NewReader(tail[i:]))
+		if err == nil {
+			return page.GranulePosition, nil
+		}
+	}
+
+	return 0, fmt.Errorf("ogg: no page found")
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// page encodes a page holding the segments. The checksum is not computed as
+// it's not verified by the reader.
+func page(continued bool, granulePosition uint64, segments ...[]byte) []byte {
+	header := make([]byte, pageHeaderSize)
+	copy(header, capturePattern)
+	if continued {
+		header[5] = 0x01
+	}
+	binary.LittleEndian.PutUint64(header[6:14], granulePosition)
+	header[26] = byte(len(segments))
+
+	var buffer bytes.Buffer
+	buffer.Write(header)
+	for _, segment := range segments {
+		buffer.WriteByte(byte(len(segment)))
+	}
+	for _, segment := range segments {
+		buffer.Write(segment)
+	}
+	return buffer.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	long := bytes.Repeat([]byte{0xAA}, 255)
+
+	var stream bytes.Buffer
+	// Two packets in one page
+	stream.Write(page(false, 0, []byte("first"), []byte("second")))
+	// A packet spanning two pages
+	stream.Write(page(false, 960, long))
+	stream.Write(page(true, 1920, long, []byte("end")))
+
+	reader := NewReader(&stream)
+
+	packet, err := reader.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), packet)
+
+	packet, err = reader.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), packet)
+
+	packet, err = reader.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, append(append(append([]byte{}, long...), long...), []byte("end")...), packet)
+	assert.Equal(t, uint64(1920), reader.GranulePosition())
+
+	_, err = reader.ReadPacket()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderSkipsUnknownContinuation(t *testing.T) {
+	long := bytes.Repeat([]byte{0xAA}, 255)
+
+	var stream bytes.Buffer
+	stream.Write(page(true, 0, long))
+	stream.Write(page(true, 0, []byte("rest"), []byte("packet")))
+
+	reader := NewReader(&stream)
+
+	packet, err := reader.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("packet"), packet)
+}
+
+func TestReaderInvalid(t *testing.T) {
+	reader := NewReader(bytes.NewReader([]byte("not an ogg stream, but long enough")))
+	_, err := reader.ReadPacket()
+	assert.Error(t, err)
+}
+
+func TestLastGranulePosition(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(page(false, 960, []byte("first")))
+	stream.Write(page(false, 1920, []byte("second")))
+
+	position, err := LastGranulePosition(bytes.NewReader(stream.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1920), position)
+}
@@ -0,0 +1,37 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirstAndPick(t *testing.T) {
+	items := []string{"a", "b", "c"}
+
+	output, err := Render(`{{ range first . 2 }}{{ . }}{{ end }}`, items)
+	require.NoError(t, err)
+	assert.Equal(t, "ab", output)
+
+	output, err = Render(`{{ range first . 5 }}{{ . }}{{ end }}`, items)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", output)
+
+	output, err = Render(`{{ len (pick . 5) }}`, items)
+	require.NoError(t, err)
+	assert.Equal(t, "3", output)
+
+	output, err = Render(`{{ len (first . 5) }}`, []string{})
+	require.NoError(t, err)
+	assert.Equal(t, "0", output)
+
+	_, err = Render(`{{ first . -1 }}`, items)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(`{{ range first .history 5 }}{{ .name }}{{ end }}`))
+	assert.Error(t, Validate(`{{ range first .history 5 }}`))
+	assert.Error(t, Validate(`{{ unknown .history }}`))
+}
@@ -0,0 +1,135 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
+)
+
+// reservedSources are the sources of the built-in providers, which are never
+// provided by the generic provider.
+var reservedSources = []state.Source{state.SourceYouTube, state.SourceLocal, state.SourceRadio}
+
+var _ MultiSourceProvider = (*Generic)(nil)
+
+// Generic provides media of any site supported by yt-dlp, such as SoundCloud,
+// Bandcamp and Vimeo. Tracks use the name of the site's extractor as their
+// source.
+type Generic struct {
+	config func() *state.Config
+}
+
+// NewGeneric returns a generic provider. Playlists are capped to the limit
+// configured in config.
+func NewGeneric(config func() *state.Config) *Generic {
+	return &Generic{
+		config: config,
+	}
+}
+
+// GenericTrack returns the track of media resolved by yt-dlp.
+func GenericTrack(info ytdlp.Info) Track {
+	source := state.Source(strings.ToLower(info.Extractor))
+	if source == "" {
+		source = state.SourceGeneric
+	}
+
+	track := Track{
+		Source:   source,
+		URI:      info.URL,
+		Title:    info.Title,
+		Artist:   info.Uploader,
+		Duration: info.Duration,
+	}
+
+	// Links which redirect to YouTube are resolved to YouTube videos
+	if source == state.SourceYouTube && info.ID != "" {
+		track.URI = info.ID
+	}
+
+	return track
+}
+
+// Source implements Provider.
+func (p *Generic) Source() state.Source {
+	return state.SourceGeneric
+}
+
+// Provides implements MultiSourceProvider.
+func (p *Generic) Provides(source state.Source) bool {
+	return !slices.Contains(reservedSources, source)
+}
+
+// Search implements Provider. Sites can't be searched.
+func (p *Generic) Search(ctx context.Context, query string, n int) ([]Track, error) {
+	return []Track{}, nil
+}
+
+// Resolve implements Provider. Any HTTP link is handled. Links which yt-dlp
+// fails to resolve resolve to no tracks. Playlists are capped to the
+// configured limit.
+func (p *Generic) Resolve(ctx context.Context, uri string) ([]Track, bool, error) {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, false, nil
+	}
+
+	slog.Debug("Resolving link using yt-dlp", slog.String("url", uri))
+	infos, err := ytdlp.Playlist(ctx, uri, p.config().PlaylistLimit)
+	var ytdlpErr ytdlp.Error
+	if errors.Is(err, ytdlp.ErrRateLimited) {
+		// The link may well be valid, so don't pretend it's empty
+		return nil, true, err
+	} else if errors.As(err, &ytdlpErr) {
+		slog.Debug("Failed to resolve link using yt-dlp", slog.String("url", uri), slog.String("stderr", ytdlpErr.Stderr))
+		return []Track{}, true, nil
+	} else if err != nil {
+		return nil, true, err
+	}
+
+	tracks := make([]Track, 0, len(infos))
+	for _, info := range infos {
+		if info.URL != "" {
+			tracks = append(tracks, GenericTrack(info))
+		}
+	}
+
+	return tracks, true, nil
+}
+
+// Stream implements Provider. Opus audio is streamed as-is unless the volume
+// is to be changed, other formats are transcoded using ffmpeg.
+func (p *Generic) Stream(ctx context.Context, uri string, options *StreamOptions) (Stream, error) {
+	info, err := ytdlp.Metadata(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.HasOpus() && options.passthrough() {
+		return newWebMStream(ctx, func(ctx context.Context, w io.Writer) error {
+			return ytdlp.Stream(ctx, uri, w)
+		}), nil
+	}
+
+	slog.Debug("Transcoding stream", slog.String("url", uri))
+	return newTranscodedStream(ctx, func(ctx context.Context, w io.Writer) error {
+		return ytdlp.StreamAny(ctx, uri, w)
+	}, options.transcodeOptions()), nil
+}
+
+// Metadata implements Provider.
+func (p *Generic) Metadata(ctx context.Context, uri string) (Track, error) {
+	info, err := ytdlp.Metadata(ctx, uri)
+	if err != nil {
+		return Track{}, err
+	}
+
+	return GenericTrack(info), nil
+}
@@ -0,0 +1,39 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericTrack(t *testing.T) {
+	track := GenericTrack(ytdlp.Info{
+		ID:        "1",
+		Title:     "Song",
+		Uploader:  "Artist",
+		Duration:  3 * time.Minute,
+		Extractor: "Soundcloud",
+		URL:       "https://soundcloud.com/artist/song",
+	})
+	assert.Equal(t, Track{Source: "soundcloud", URI: "https://soundcloud.com/artist/song", Title: "Song", Artist: "Artist", Duration: 3 * time.Minute}, track)
+
+	track = GenericTrack(ytdlp.Info{ID: "dQw4w9WgXcQ", Extractor: "Youtube", URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"})
+	assert.Equal(t, state.SourceYouTube, track.Source)
+	assert.Equal(t, "dQw4w9WgXcQ", track.URI)
+}
+
+func TestGeneric(t *testing.T) {
+	provider := NewGeneric(state.DefaultConfig)
+
+	assert.True(t, provider.Provides("soundcloud"))
+	assert.False(t, provider.Provides(state.SourceLocal))
+
+	_, ok, err := provider.Resolve(context.Background(), "local:Artist")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,317 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/ffmpeg"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/tags"
+)
+
+// localPrefix is the prefix of URIs referring to files or directories of the
+// library, such as "local:Artist/Album".
+const localPrefix = "local:"
+
+// localExtensions are the extensions of the files to index. Files which are
+// not FLAC or Ogg are indexed using their file name.
+var localExtensions = []string{".flac", ".opus", ".ogg", ".oga", ".mp3", ".m4a", ".aac", ".wav"}
+
+var _ Runner = (*Local)(nil)
+
+// localTrack is an indexed file of the library.
+type localTrack struct {
+	Track
+	Album       string
+	TrackNumber int
+	// keywords holds the lower cased artist, title, album and path, used to
+	// search for the track.
+	keywords string
+}
+
+// Local provides files of a local music library.
+type Local struct {
+	root   string
+	rescan time.Duration
+
+	mutex  sync.RWMutex
+	tracks []localTrack
+}
+
+// NewLocal returns a provider of the music library at root. The library is
+// indexed by Run.
+func NewLocal(root string, rescan time.Duration) *Local {
+	return &Local{
+		root:   root,
+		rescan: rescan,
+	}
+}
+
+// Run indexes the library and, if configured, rescans it periodically until
+// the context is cancelled.
+func (p *Local) Run(ctx context.Context) {
+	if err := p.Index(); err != nil {
+		slog.Error("Failed to index music library", slog.String("path", p.root), slog.Any("error", err))
+	}
+
+	if p.rescan == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.rescan)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Index(); err != nil {
+				slog.Error("Failed to index music library", slog.String("path", p.root), slog.Any("error", err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Index indexes the files of the library.
+func (p *Local) Index() error {
+	slog.Debug("Indexing music library", slog.String("path", p.root))
+	started := time.Now()
+
+	tracks := make([]localTrack, 0)
+	err := filepath.WalkDir(p.root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable directories rather than failing the entire index,
+			// unless it's the library itself
+			if entry != nil && entry.IsDir() && name != p.root {
+				slog.Warn("Failed to read directory of music library", slog.String("path", name), slog.Any("error", err))
+				return fs.SkipDir
+			}
+			return err
+		}
+
+		if entry.IsDir() || !slices.Contains(localExtensions, strings.ToLower(filepath.Ext(name))) {
+			return nil
+		}
+
+		relative, err := filepath.Rel(p.root, name)
+		if err != nil {
+			return err
+		}
+
+		tracks = append(tracks, readLocalTrack(name, filepath.ToSlash(relative)))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sortLocalTracks(tracks)
+
+	p.mutex.Lock()
+	p.tracks = tracks
+	p.mutex.Unlock()
+
+	slog.Debug("Indexed music library", slog.String("path", p.root), slog.Int("tracks", len(tracks)), slog.Duration("duration", time.Since(started)))
+	return nil
+}
+
+// sortLocalTracks sorts tracks by artist, album, track number and path.
+func sortLocalTracks(tracks []localTrack) {
+	slices.SortFunc(tracks, func(a localTrack, b localTrack) int {
+		if c := strings.Compare(a.Artist, b.Artist); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Album, b.Album); c != 0 {
+			return c
+		}
+		if a.TrackNumber != b.TrackNumber {
+			return a.TrackNumber - b.TrackNumber
+		}
+		return strings.Compare(a.URI, b.URI)
+	})
+}
+
+// readLocalTrack reads the tags of a file.
+func readLocalTrack(name string, uri string) localTrack {
+	metadata, err := tags.Read(name)
+	if err != nil && !errors.Is(err, tags.ErrUnsupportedFormat) {
+		slog.Warn("Failed to read tags", slog.String("path", name), slog.Any("error", err))
+	}
+
+	return newLocalTrack(uri, metadata)
+}
+
+// newLocalTrack returns the track of a file with the tags. Files without tags
+// are named after the file.
+func newLocalTrack(uri string, metadata tags.Tags) localTrack {
+	title := metadata.Title
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(uri), path.Ext(uri))
+	}
+
+	// Titles are shown as-is elsewhere, so include the artist
+	if metadata.Artist != "" {
+		title = metadata.Artist + " - " + title
+	}
+
+	return localTrack{
+		Track: Track{
+			Source:   state.SourceLocal,
+			URI:      uri,
+			Title:    title,
+			Artist:   metadata.Artist,
+			Duration: metadata.Duration,
+		},
+		Album:       metadata.Album,
+		TrackNumber: metadata.TrackNumber,
+		keywords:    strings.ToLower(strings.Join([]string{title, metadata.Album, uri}, " ")),
+	}
+}
+
+// Source implements Provider.
+func (p *Local) Source() state.Source {
+	return state.SourceLocal
+}
+
+// Search implements Provider. Tracks matching all words of the query by
+// artist, title, album or path are returned. Tracks with titles matching the
+// query are returned first.
+func (p *Local) Search(ctx context.Context, query string, n int) ([]Track, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return []Track{}, nil
+	}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	titleMatches := make([]Track, 0)
+	otherMatches := make([]Track, 0)
+	for _, track := range p.tracks {
+		if !containsAll(track.keywords, words) {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(track.Title), query) {
+			titleMatches = append(titleMatches, track.Track)
+		} else {
+			otherMatches = append(otherMatches, track.Track)
+		}
+	}
+
+	return append(titleMatches, otherMatches...), nil
+}
+
+// Resolve implements Provider. URIs such as "local:Artist/Album" refer to a
+// file or all files of a directory of the library.
+func (p *Local) Resolve(ctx context.Context, uri string) ([]Track, bool, error) {
+	name, ok := strings.CutPrefix(uri, localPrefix)
+	if !ok {
+		return nil, false, nil
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	tracks := make([]Track, 0)
+	for _, track := range p.tracks {
+		if name == "" || track.URI == name || strings.HasPrefix(track.URI, name+"/") {
+			tracks = append(tracks, track.Track)
+		}
+	}
+
+	return tracks, true, nil
+}
+
+// Stream implements Provider. Ogg Opus files are streamed as-is unless the
+// volume is to be changed, other files are transcoded using ffmpeg.
+func (p *Local) Stream(ctx context.Context, uri string, options *StreamOptions) (Stream, error) {
+	// Files are streamed even if not yet indexed, such as when the library is
+	// being indexed on startup. Make sure the path stays within the library
+	name := filepath.Join(p.root, filepath.FromSlash(path.Clean("/"+uri)))
+	if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if extension := strings.ToLower(filepath.Ext(name)); options.passthrough() && (extension == ".opus" || extension == ".ogg" || extension == ".oga") {
+		stream, err := openOpusFile(name)
+		if err != nil {
+			return nil, err
+		} else if stream != nil {
+			return stream, nil
+		}
+		slog.Debug("Opus file is not streamable as-is, transcoding", slog.String("path", name))
+	}
+
+	transcodeOptions := options.transcodeOptions()
+	return newWebMStream(ctx, func(ctx context.Context, w io.Writer) error {
+		return ffmpeg.Transcode(ctx, name, w, transcodeOptions)
+	}), nil
+}
+
+// openOpusFile opens an Ogg Opus file for streaming. Returns nil if the file
+// has to be transcoded, such as if it's an Ogg Vorbis file or if it's not
+// encoded in frames of 20ms.
+func openOpusFile(name string) (Stream, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := openOpus(file, file)
+	if err != nil || stream == nil {
+		file.Close()
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+// Metadata implements Provider.
+func (p *Local) Metadata(ctx context.Context, uri string) (Track, error) {
+	track, ok := p.track(uri)
+	if !ok {
+		return Track{}, ErrNotFound
+	}
+
+	return track.Track, nil
+}
+
+// track returns the indexed track of a URI.
+func (p *Local) track(uri string) (localTrack, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for _, track := range p.tracks {
+		if track.URI == uri {
+			return track, true
+		}
+	}
+
+	return localTrack{}, false
+}
+
+// containsAll returns whether or not s contains all words.
+func containsAll(s string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(s, word) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// opusFile encodes an Ogg Opus file holding the packets, each in their own
+// page.
+func opusFile(packets ...[]byte) []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = 2
+
+	// OpusTags without a vendor, holding the comment TITLE=Opus
+	tags := append([]byte("OpusTags\x00\x00\x00\x00\x01\x00\x00\x00\x0a\x00\x00\x00"), "TITLE=Opus"...)
+
+	var file bytes.Buffer
+	for i, packet := range append([][]byte{head, tags}, packets...) {
+		header := make([]byte, 27)
+		copy(header, "OggS")
+		binary.LittleEndian.PutUint64(header[6:14], uint64(i*960))
+		header[26] = 1
+		file.Write(header)
+		file.WriteByte(byte(len(packet)))
+		file.Write(packet)
+	}
+	return file.Bytes()
+}
+
+func TestLocal(t *testing.T) {
+	root := t.TempDir()
+	files := map[string][]byte{
+		"Other/untagged.mp3": []byte("ID3"),
+		"Other/cover.jpg":    []byte("not music"),
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), content, 0644))
+	}
+
+	provider := NewLocal(root, 0)
+	require.NoError(t, provider.Index())
+
+	// Reading tags is tested by the tags package, so add tagged tracks as if
+	// they were indexed
+	provider.tracks = append(provider.tracks,
+		newLocalTrack("Artist/Album/02 Second.flac", tags.Tags{Title: "Second Song", Artist: "Artist", Album: "Album", TrackNumber: 2, Duration: time.Second}),
+		newLocalTrack("Artist/Album/01 First.flac", tags.Tags{Title: "First Song", Artist: "Artist", Album: "Album", TrackNumber: 1, Duration: time.Second}),
+	)
+	sortLocalTracks(provider.tracks)
+
+	first := Track{
+		Source:   state.SourceLocal,
+		URI:      "Artist/Album/01 First.flac",
+		Title:    "Artist - First Song",
+		Artist:   "Artist",
+		Duration: time.Second,
+	}
+	second := Track{
+		Source:   state.SourceLocal,
+		URI:      "Artist/Album/02 Second.flac",
+		Title:    "Artist - Second Song",
+		Artist:   "Artist",
+		Duration: time.Second,
+	}
+	untagged := Track{
+		Source: state.SourceLocal,
+		URI:    "Other/untagged.mp3",
+		Title:  "untagged",
+	}
+
+	tracks, err := provider.Search(context.Background(), "second artist", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []Track{second}, tracks)
+
+	// Matches by album
+	tracks, err = provider.Search(context.Background(), "album", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []Track{first, second}, tracks)
+
+	tracks, err = provider.Search(context.Background(), "untagged", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []Track{untagged}, tracks)
+
+	tracks, ok, err := provider.Resolve(context.Background(), "local:Artist/Album/")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []Track{first, second}, tracks)
+
+	// Tracks are sorted by artist, album and track number. Other files are not
+	// indexed
+	tracks, ok, err = provider.Resolve(context.Background(), "local:")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []Track{untagged, first, second}, tracks)
+
+	_, ok, err = provider.Resolve(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	track, err := provider.Metadata(context.Background(), "Other/untagged.mp3")
+	require.NoError(t, err)
+	assert.Equal(t, untagged, track)
+
+	_, err = provider.Metadata(context.Background(), "Other/missing.mp3")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = provider.Stream(context.Background(), "../../etc/passwd", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStreamOpus(t *testing.T) {
+	root := t.TempDir()
+
+	// Two CELT packets of 20ms
+	frames := [][]byte{{31 << 3, 0x01}, {31 << 3, 0x02}}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "song.opus"), opusFile(frames...), 0644))
+
+	provider := NewLocal(root, 0)
+	stream, err := provider.Stream(context.Background(), "song.opus", nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	for _, expected := range frames {
+		frame, err := stream.ReadFrame()
+		require.NoError(t, err)
+		assert.Equal(t, expected, frame)
+	}
+
+	_, err = stream.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLocalUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced for root")
+	}
+
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "Readable"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Readable", "song.mp3"), []byte("ID3"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "Unreadable"), os.ModePerm))
+	require.NoError(t, os.Chmod(filepath.Join(root, "Unreadable"), 0))
+	t.Cleanup(func() { os.Chmod(filepath.Join(root, "Unreadable"), os.ModePerm) })
+
+	provider := NewLocal(root, 0)
+	require.NoError(t, provider.Index())
+
+	tracks, err := provider.Search(context.Background(), "song", 10)
+	require.NoError(t, err)
+	assert.Len(t, tracks, 1)
+}
+
+func TestLocalRun(t *testing.T) {
+	root := t.TempDir()
+	provider := NewLocal(root, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewRegistry(provider).Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "song.mp3"), []byte("ID3"), 0644))
+	assert.Eventually(t, func() bool {
+		tracks, err := provider.Search(context.Background(), "song", 10)
+		return err == nil && len(tracks) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not return once cancelled")
+	}
+}
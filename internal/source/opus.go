@@ -0,0 +1,90 @@
+package source
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/ogg"
+)
+
+// opusFrameDuration is the duration of the OPUS frames sent to Discord.
+const opusFrameDuration = 20 * time.Millisecond
+
+// opusFrameSizes holds the frame size of each OPUS configuration.
+// SEE: https://www.rfc-editor.org/rfc/rfc6716#section-3.1
+var opusFrameSizes = [32]time.Duration{
+	// SILK-only
+	10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond,
+	// Hybrid
+	10 * time.Millisecond, 20 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond,
+	// CELT-only
+	2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond,
+	2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond,
+	2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond,
+	2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond,
+}
+
+// opusPacketDuration returns the duration of an OPUS packet. Returns zero for
+// invalid packets.
+func opusPacketDuration(packet []byte) time.Duration {
+	if len(packet) == 0 {
+		return 0
+	}
+
+	toc := packet[0]
+	frameSize := opusFrameSizes[toc>>3]
+
+	switch toc & 0x03 {
+	case 0:
+		return frameSize
+	case 1, 2:
+		return 2 * frameSize
+	default:
+		if len(packet) < 2 {
+			return 0
+		}
+		return time.Duration(packet[1]&0x3F) * frameSize
+	}
+}
+
+// isDiscordOpusHead returns whether or not an Ogg Opus stream identified by
+// the OpusHead packet can be sent to Discord as-is, which requires mono or
+// stereo audio.
+func isDiscordOpusHead(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("OpusHead")) && len(head) >= 19 && head[9] >= 1 && head[9] <= 2
+}
+
+// openOpus reads the headers of an Ogg Opus stream. Returns nil if the stream
+// has to be transcoded, such as if it's an Ogg Vorbis stream or if it's not
+// encoded in frames of 20ms. The closer is left open if no stream is
+// returned.
+func openOpus(r io.Reader, closer io.Closer) (*oggStream, error) {
+	reader := ogg.NewReader(r)
+	head, err := reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	} else if !isDiscordOpusHead(head) {
+		return nil, nil
+	}
+
+	tags, err := reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	if opusPacketDuration(first) != opusFrameDuration {
+		return nil, nil
+	}
+
+	// Let the stream handle the tags like those of any chained stream
+	return newOggStream(reader, closer, tags, first), nil
+}
@@ -0,0 +1,29 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpusPacketDuration(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Packet   []byte
+		Expected time.Duration
+	}{
+		{Name: "empty", Packet: []byte{}, Expected: 0},
+		{Name: "celt 20ms", Packet: []byte{31<<3 | 0}, Expected: 20 * time.Millisecond},
+		{Name: "silk 60ms", Packet: []byte{3<<3 | 0}, Expected: 60 * time.Millisecond},
+		{Name: "two celt 10ms frames", Packet: []byte{30<<3 | 1}, Expected: 20 * time.Millisecond},
+		{Name: "four celt 5ms frames", Packet: []byte{29<<3 | 3, 4}, Expected: 20 * time.Millisecond},
+		{Name: "truncated", Packet: []byte{29<<3 | 3}, Expected: 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, opusPacketDuration(testCase.Packet))
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlexGustafsson/clabbe/internal/ffmpeg"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+)
+
+// radioTimeout is the time to wait for a station to respond.
+const radioTimeout = 10 * time.Second
+
+var (
+	ErrNotRadio = errors.New("not a radio stream")
+)
+
+var _ Provider = (*Radio)(nil)
+
+// Radio provides live HTTP audio streams, such as Icecast radio stations. Ogg
+// Opus streams are streamed as-is, other streams are transcoded using ffmpeg.
+type Radio struct {
+	client *http.Client
+}
+
+// NewRadio returns a radio provider.
+func NewRadio() *Radio {
+	// Streams never end, so only time out while waiting for a response
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = radioTimeout
+
+	return &Radio{
+		client: &http.Client{
+			Transport: transport,
+		},
+	}
+}
+
+// Source implements Provider.
+func (p *Radio) Source() state.Source {
+	return state.SourceRadio
+}
+
+// Search implements Provider. Stations can't be searched.
+func (p *Radio) Search(ctx context.Context, query string, n int) ([]Track, error) {
+	return []Track{}, nil
+}
+
+// Resolve implements Provider. Links are not handled, as any link could be a
+// stream. Use Metadata to look up a station.
+func (p *Radio) Resolve(ctx context.Context, uri string) ([]Track, bool, error) {
+	return nil, false, nil
+}
+
+// Stream implements Provider. ICY metadata, if any, is stripped from the
+// stream and reported using the options' OnTitle. Ogg Opus streams are
+// streamed as-is unless the volume is to be changed.
+func (p *Radio) Stream(ctx context.Context, uri string, options *StreamOptions) (Stream, error) {
+	if options == nil {
+		options = &StreamOptions{Volume: 100}
+	}
+
+	res, err := p.open(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader = res.Body
+	if interval, err := strconv.Atoi(res.Header.Get("icy-metaint")); err == nil && interval > 0 {
+		reader = newICYReader(reader, interval, options.OnTitle)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if options.passthrough() && (mediaType == "application/ogg" || mediaType == "audio/ogg" || mediaType == "audio/opus") {
+		recorder := &recordingReader{reader: reader, recording: true}
+		stream, err := openOpus(recorder, res.Body)
+		if err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		recorder.recording = false
+
+		if stream != nil {
+			stream.onTitle = options.OnTitle
+			return stream, nil
+		}
+
+		slog.Debug("Ogg stream is not streamable as-is, transcoding", slog.String("url", uri))
+		reader = io.MultiReader(&recorder.recorded, reader)
+	}
+
+	return newWebMStream(ctx, func(ctx context.Context, w io.Writer) error {
+		defer res.Body.Close()
+		return ffmpeg.TranscodeReader(ctx, reader, w, options.transcodeOptions())
+	}), nil
+}
+
+// Metadata implements Provider. The station is named after its ICY name, if
+// any. Returns ErrNotRadio if uri does not refer to an audio stream.
+func (p *Radio) Metadata(ctx context.Context, uri string) (Track, error) {
+	ctx, cancel := context.WithTimeout(ctx, radioTimeout)
+	defer cancel()
+
+	res, err := p.open(ctx, uri)
+	if err != nil {
+		return Track{}, err
+	}
+	res.Body.Close()
+
+	title := strings.TrimSpace(res.Header.Get("icy-name"))
+	if title == "" {
+		u, _ := url.Parse(uri)
+		title = u.Host + u.Path
+	}
+
+	return Track{
+		Source: state.SourceRadio,
+		URI:    uri,
+		Title:  title,
+		Live:   true,
+	}, nil
+}
+
+// open requests the stream of a station. Returns ErrNotRadio if uri does not
+// refer to an audio stream.
+func (p *Radio) open(ctx context.Context, uri string) (*http.Response, error) {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, ErrNotRadio
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Request the titles of the songs played
+	req.Header.Set("Icy-MetaData", "1")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	} else if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("radio: unexpected status code %d", res.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if !isRadioMediaType(mediaType) {
+		res.Body.Close()
+		return nil, ErrNotRadio
+	}
+
+	return res, nil
+}
+
+// isRadioMediaType returns whether or not the media type is that of an audio
+// stream. Playlists, such as M3U and PLS files, are not streams.
+func isRadioMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/ogg":
+		return true
+	case "audio/mpegurl", "audio/x-mpegurl", "audio/x-scpls":
+		return false
+	default:
+		return strings.HasPrefix(mediaType, "audio/")
+	}
+}
+
+// icyReader strips ICY metadata from a stream.
+// SEE: https://cast.readme.io/docs/icy
+type icyReader struct {
+	reader io.Reader
+	// interval is the number of bytes of audio between metadata blocks.
+	interval int
+	// remaining is the number of bytes of audio left until the next metadata
+	// block.
+	remaining int
+	onTitle   func(title string)
+}
+
+// newICYReader returns a reader of the audio of a stream holding metadata
+// every interval bytes. Titles are reported to onTitle, if non-nil.
+func newICYReader(reader io.Reader, interval int, onTitle func(title string)) *icyReader {
+	return &icyReader{
+		reader:    reader,
+		interval:  interval,
+		remaining: interval,
+		onTitle:   onTitle,
+	}
+}
+
+// Read implements io.Reader.
+func (r *icyReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		if err := r.readMetadata(); err != nil {
+			return 0, err
+		}
+		r.remaining = r.interval
+	}
+
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= n
+	return n, err
+}
+
+// readMetadata reads a metadata block. The block is prefixed by its length in
+// units of 16 bytes. Empty blocks mean the metadata is unchanged.
+func (r *icyReader) readMetadata() error {
+	var length [1]byte
+	if _, err := io.ReadFull(r.reader, length[:]); err != nil {
+		return err
+	}
+
+	if length[0] == 0 {
+		return nil
+	}
+
+	metadata := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(r.reader, metadata); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if title, ok := parseICYTitle(metadata); ok && r.onTitle != nil {
+		r.onTitle(title)
+	}
+
+	return nil
+}
+
+// parseICYTitle returns the title of ICY metadata, such as
+// "StreamTitle='Artist - Title';". Titles which aren't UTF-8 are assumed to be
+// Latin-1.
+func parseICYTitle(metadata []byte) (string, bool) {
+	metadata = bytes.TrimRight(metadata, "\x00")
+
+	_, title, ok := bytes.Cut(metadata, []byte("StreamTitle='"))
+	if !ok {
+		return "", false
+	}
+
+	// Titles may hold quotes, so look for the end of the field
+	if end := bytes.Index(title, []byte("';")); end >= 0 {
+		title = title[:end]
+	} else {
+		title = bytes.TrimSuffix(title, []byte("'"))
+	}
+
+	if utf8.Valid(title) {
+		return strings.TrimSpace(string(title)), true
+	}
+
+	runes := make([]rune, len(title))
+	for i, b := range title {
+		runes[i] = rune(b)
+	}
+	return strings.TrimSpace(string(runes)), true
+}
+
+// recordingReader records the data read from reader while recording.
+type recordingReader struct {
+	reader    io.Reader
+	recording bool
+	recorded  bytes.Buffer
+}
+
+// Read implements io.Reader.
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if r.recording {
+		r.recorded.Write(p[:n])
+	}
+	return n, err
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF in place of io.EOF, as a stream
+// ending mid-block is truncated.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
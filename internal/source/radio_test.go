@@ -0,0 +1,137 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// icyStream interleaves the metadata with the data every interval bytes.
+func icyStream(data []byte, interval int, metadata string) []byte {
+	block := []byte(metadata)
+	if padding := len(block) % 16; padding > 0 {
+		block = append(block, make([]byte, 16-padding)...)
+	}
+
+	var stream bytes.Buffer
+	for len(data) > interval {
+		stream.Write(data[:interval])
+		data = data[interval:]
+		stream.WriteByte(byte(len(block) / 16))
+		stream.Write(block)
+	}
+	stream.Write(data)
+	return stream.Bytes()
+}
+
+func TestParseICYTitle(t *testing.T) {
+	testCases := []struct {
+		Metadata string
+		Expected string
+		OK       bool
+	}{
+		{Metadata: "StreamTitle='Artist - Title';StreamUrl='';\x00\x00", Expected: "Artist - Title", OK: true},
+		{Metadata: "StreamTitle='Don't Stop';", Expected: "Don't Stop", OK: true},
+		{Metadata: "StreamTitle='Last'", Expected: "Last", OK: true},
+		{Metadata: "StreamTitle='Caf\xe9';", Expected: "Café", OK: true},
+		{Metadata: "StreamUrl='';", OK: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Metadata, func(t *testing.T) {
+			title, ok := parseICYTitle([]byte(testCase.Metadata))
+			assert.Equal(t, testCase.OK, ok)
+			assert.Equal(t, testCase.Expected, title)
+		})
+	}
+}
+
+func TestICYReader(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 10))
+
+	titles := make([]string, 0)
+	reader := newICYReader(bytes.NewReader(icyStream(data, 16, "StreamTitle='Song';")), 16, func(title string) {
+		titles = append(titles, title)
+	})
+
+	read, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, read)
+	assert.Len(t, titles, len(data)/16)
+	assert.Equal(t, "Song", titles[0])
+}
+
+func TestRadio(t *testing.T) {
+	// Two CELT packets of 20ms
+	frames := [][]byte{{31 << 3, 0x01}, {31 << 3, 0x02}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stream.opus":
+			w.Header().Set("Content-Type", "audio/ogg")
+			w.Header().Set("icy-name", "Station")
+			if r.Header.Get("Icy-MetaData") == "1" {
+				w.Header().Set("icy-metaint", "32")
+				w.Write(icyStream(opusFile(frames...), 32, "StreamTitle='Artist - Song';"))
+			} else {
+				w.Write(opusFile(frames...))
+			}
+		case "/index.html":
+			w.Header().Set("Content-Type", "text/html")
+		case "/stream.m3u":
+			w.Header().Set("Content-Type", "audio/x-mpegurl")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewRadio()
+
+	track, err := provider.Metadata(context.Background(), server.URL+"/stream.opus")
+	require.NoError(t, err)
+	assert.Equal(t, Track{Source: state.SourceRadio, URI: server.URL + "/stream.opus", Title: "Station", Live: true}, track)
+
+	_, err = provider.Metadata(context.Background(), server.URL+"/index.html")
+	assert.ErrorIs(t, err, ErrNotRadio)
+
+	_, err = provider.Metadata(context.Background(), server.URL+"/stream.m3u")
+	assert.ErrorIs(t, err, ErrNotRadio)
+
+	_, err = provider.Metadata(context.Background(), server.URL+"/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = provider.Metadata(context.Background(), "ftp://example.com/stream.mp3")
+	assert.ErrorIs(t, err, ErrNotRadio)
+
+	titles := make([]string, 0)
+	stream, err := provider.Stream(context.Background(), server.URL+"/stream.opus", &StreamOptions{
+		Volume: 100,
+		OnTitle: func(title string) {
+			titles = append(titles, title)
+		},
+	})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	for _, expected := range frames {
+		frame, err := stream.ReadFrame()
+		require.NoError(t, err)
+		assert.Equal(t, expected, frame)
+	}
+
+	_, err = stream.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+
+	// The stream's own tags are reported along with the ICY metadata
+	assert.Contains(t, titles, "Opus")
+	assert.Contains(t, titles, "Artist - Song")
+}
@@ -0,0 +1,129 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+)
+
+// Registry holds the available providers.
+type Registry struct {
+	providers []Provider
+}
+
+// NewRegistry returns a registry holding the providers. Providers are
+// searched in order.
+func NewRegistry(providers ...Provider) *Registry {
+	return &Registry{
+		providers: providers,
+	}
+}
+
+// FromConfig returns a registry holding the providers configured in config,
+// followed by the YouTube, radio and generic providers. Configured sources are
+// thereby preferred when searching, with YouTube as the fallback. Links not
+// handled by any other provider are resolved by the generic provider.
+//
+// The config function returns the current config. Providers call it whenever
+// they need it, so that reloaded configs apply without recreating them.
+func FromConfig(config func() *state.Config) (*Registry, error) {
+	registry := NewRegistry()
+
+	for i, sourceConfig := range config().Sources {
+		provider, err := New(sourceConfig)
+		if err != nil {
+			return nil, fmt.Errorf("sources[%d]: %w", i, err)
+		}
+
+		if err := registry.Register(provider); err != nil {
+			return nil, fmt.Errorf("sources[%d]: %w", i, err)
+		}
+	}
+
+	if err := registry.Register(NewYouTube(config)); err != nil {
+		return nil, err
+	}
+
+	if err := registry.Register(NewRadio()); err != nil {
+		return nil, err
+	}
+
+	if err := registry.Register(NewGeneric(config)); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// New returns a provider as configured.
+func New(config state.SourceConfig) (Provider, error) {
+	switch config.Type {
+	case "local":
+		return NewLocal(config.Path, config.Rescan), nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %q", config.Type)
+	}
+}
+
+// Run runs the background work of the providers implementing Runner until the
+// context is cancelled.
+func (r *Registry) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, provider := range r.providers {
+		if runner, ok := provider.(Runner); ok {
+			wg.Go(func() { runner.Run(ctx) })
+		}
+	}
+	wg.Wait()
+}
+
+// Register adds a provider. Returns an error if a provider of the same source
+// is already registered.
+func (r *Registry) Register(provider Provider) error {
+	if _, ok := r.Provider(provider.Source()); ok {
+		return fmt.Errorf("source %q is already registered", provider.Source())
+	}
+
+	r.providers = append(r.providers, provider)
+	return nil
+}
+
+// Provider returns the provider of a source. Providers of the source itself
+// are preferred over providers of several sources.
+func (r *Registry) Provider(source state.Source) (Provider, bool) {
+	for _, provider := range r.providers {
+		if provider.Source() == source {
+			return provider, true
+		}
+	}
+
+	for _, provider := range r.providers {
+		if provider, ok := provider.(MultiSourceProvider); ok && provider.Provides(source) {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
+
+// Providers returns the registered providers, in the order they're searched.
+func (r *Registry) Providers() []Provider {
+	return r.providers
+}
+
+// Resolve resolves the tracks of a URI using the first provider that handles
+// it. Returns false if no provider handles the URI.
+func (r *Registry) Resolve(ctx context.Context, uri string) ([]Track, bool, error) {
+	for _, provider := range r.providers {
+		tracks, ok, err := provider.Resolve(ctx, uri)
+		if err != nil {
+			return nil, true, err
+		} else if ok {
+			return tracks, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
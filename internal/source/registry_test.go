@@ -0,0 +1,92 @@
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Provider = (*fakeProvider)(nil)
+
+// fakeProvider resolves URIs prefixed with its source.
+type fakeProvider struct {
+	source state.Source
+}
+
+func (p *fakeProvider) Source() state.Source {
+	return p.source
+}
+
+func (p *fakeProvider) Search(ctx context.Context, query string, n int) ([]Track, error) {
+	return []Track{{Source: p.source, URI: query}}, nil
+}
+
+func (p *fakeProvider) Resolve(ctx context.Context, uri string) ([]Track, bool, error) {
+	if len(uri) < len(p.source) || uri[:len(p.source)] != string(p.source) {
+		return nil, false, nil
+	}
+
+	return []Track{{Source: p.source, URI: uri}}, true, nil
+}
+
+func (p *fakeProvider) Stream(ctx context.Context, uri string, options *StreamOptions) (Stream, error) {
+	return nil, ErrNotFound
+}
+
+func (p *fakeProvider) Metadata(ctx context.Context, uri string) (Track, error) {
+	return Track{}, ErrNotFound
+}
+
+func TestRegistry(t *testing.T) {
+	foo := &fakeProvider{source: "foo"}
+	bar := &fakeProvider{source: "bar"}
+
+	registry := NewRegistry(foo)
+	require.NoError(t, registry.Register(bar))
+	assert.Error(t, registry.Register(&fakeProvider{source: "bar"}))
+
+	assert.Equal(t, []Provider{foo, bar}, registry.Providers())
+
+	provider, ok := registry.Provider("bar")
+	assert.True(t, ok)
+	assert.Equal(t, bar, provider)
+
+	_, ok = registry.Provider("baz")
+	assert.False(t, ok)
+
+	tracks, ok, err := registry.Resolve(context.Background(), "bar:1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []Track{{Source: "bar", URI: "bar:1"}}, tracks)
+
+	_, ok, err = registry.Resolve(context.Background(), "baz:1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestFromConfig(t *testing.T) {
+	config := state.DefaultConfig()
+
+	registry, err := FromConfig(func() *state.Config { return config })
+	require.NoError(t, err)
+	require.Len(t, registry.Providers(), 3)
+	assert.Equal(t, state.SourceYouTube, registry.Providers()[0].Source())
+	assert.Equal(t, state.SourceRadio, registry.Providers()[1].Source())
+	assert.Equal(t, state.SourceGeneric, registry.Providers()[2].Source())
+
+	// Sites supported by yt-dlp are provided by the generic provider
+	provider, ok := registry.Provider("soundcloud")
+	require.True(t, ok)
+	assert.Equal(t, state.SourceGeneric, provider.Source())
+
+	// Unconfigured sources are not
+	_, ok = registry.Provider(state.SourceLocal)
+	assert.False(t, ok)
+
+	config.Sources = []state.SourceConfig{{Type: "unknown"}}
+	_, err = FromConfig(func() *state.Config { return config })
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+// Package source provides music from sources such as YouTube.
+package source
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/ffmpeg"
+	"github.com/AlexGustafsson/clabbe/internal/state"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+)
+
+// Track is a track provided by a source.
+type Track struct {
+	Source state.Source
+	// URI is a source-specific URI that uniquely refers to the track.
+	URI   string
+	Title string
+	// Artist is the name of the artist, or the uploader such as the channel of
+	// a YouTube video.
+	Artist   string
+	Duration time.Duration
+	// Views is the view count of the track. Zero if unknown.
+	Views int
+	// Live is true if the track is a livestream.
+	Live bool
+	// Verified is true if the artist is verified by the source.
+	Verified bool
+	// Official is true if the track is published by the artist, such as by an
+	// official artist channel.
+	Official bool
+}
+
+// Entry returns a playlist entry for the track.
+func (t Track) Entry(addedBy state.Entity) state.PlaylistEntry {
+	return state.PlaylistEntry{
+		Time:     time.Now(),
+		Title:    t.Title,
+		AddedBy:  addedBy,
+		Source:   t.Source,
+		URI:      t.URI,
+		Duration: t.Duration,
+	}
+}
+
+// Stream is a stream of OPUS-encoded audio.
+type Stream interface {
+	// ReadFrame returns the next frame of 20ms of audio. Returns io.EOF once
+	// the stream ends.
+	ReadFrame() ([]byte, error)
+	// Close stops the stream.
+	Close() error
+}
+
+type StreamOptions struct {
+	// Volume is the volume in percent. Streams are transcoded unless the
+	// volume is 100, which costs CPU time.
+	Volume int
+	// OnTitle is called whenever the title of a live stream changes, such as
+	// when a radio station starts playing another song. May be called from
+	// another goroutine than the one reading the stream.
+	OnTitle func(title string)
+}
+
+// transcodeOptions returns the options used to transcode streams. The volume
+// defaults to 100 if options is nil.
+func (o *StreamOptions) transcodeOptions() *ffmpeg.TranscodeOptions {
+	if o == nil {
+		return &ffmpeg.TranscodeOptions{Volume: 100}
+	}
+	return &ffmpeg.TranscodeOptions{Volume: o.Volume}
+}
+
+// passthrough returns whether or not Opus audio may be streamed as-is, which
+// is the case unless the volume is to be changed.
+func (o *StreamOptions) passthrough() bool {
+	return o.transcodeOptions().Volume == 100
+}
+
+// Provider provides music from a source.
+type Provider interface {
+	// Source returns the source of the provider's tracks.
+	Source() state.Source
+	// Search returns tracks matching the query, best match first. At least n
+	// tracks are returned, if available.
+	Search(ctx context.Context, query string, n int) ([]Track, error)
+	// Resolve resolves the tracks of a URI, such as a link to a video or
+	// playlist. Returns false if the URI is not handled by the provider.
+	Resolve(ctx context.Context, uri string) ([]Track, bool, error)
+	// Stream streams the track identified by uri. The stream is stopped once
+	// ctx is done. Nil options use the defaults.
+	Stream(ctx context.Context, uri string, options *StreamOptions) (Stream, error)
+	// Metadata returns the track identified by uri. Returns ErrNotFound if
+	// there is no such track.
+	Metadata(ctx context.Context, uri string) (Track, error)
+}
+
+// MultiSourceProvider is a provider of tracks of several sources, such as the
+// sites supported by yt-dlp.
+type MultiSourceProvider interface {
+	Provider
+	// Provides returns whether or not the provider provides tracks of the
+	// source.
+	Provides(source state.Source) bool
+}
+
+// Runner is a provider with background work, such as indexing a library.
+type Runner interface {
+	Provider
+	// Run runs the background work until the context is cancelled.
+	Run(ctx context.Context)
+}
@@ -0,0 +1,191 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/AlexGustafsson/clabbe/internal/ffmpeg"
+	"github.com/AlexGustafsson/clabbe/internal/ogg"
+	"github.com/AlexGustafsson/clabbe/internal/tags"
+	"github.com/AlexGustafsson/clabbe/internal/webm"
+)
+
+var _ Stream = (*webmStream)(nil)
+
+// webmStream is a stream of OPUS frames read from a WebM container written by
+// a producer, such as yt-dlp.
+type webmStream struct {
+	reader *io.PipeReader
+	webm   *webm.Reader
+	cancel context.CancelFunc
+
+	// done is closed once the producer returns.
+	done chan struct{}
+	// err is the error returned by the producer. Set before done is closed.
+	err error
+}
+
+// newWebMStream starts produce, which is expected to write a WebM container
+// holding OPUS audio to w.
+func newWebMStream(ctx context.Context, produce func(ctx context.Context, w io.Writer) error) *webmStream {
+	ctx, cancel := context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+
+	s := &webmStream{
+		reader: reader,
+		webm:   webm.NewReader(reader),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		err := produce(ctx, writer)
+		s.err = err
+		close(s.done)
+		// Let the remaining frames play out
+		writer.CloseWithError(err)
+	}()
+
+	return s
+}
+
+// newTranscodedStream starts produce, which may write audio of any format to
+// w, transcoding it using ffmpeg.
+func newTranscodedStream(ctx context.Context, produce func(ctx context.Context, w io.Writer) error, options *ffmpeg.TranscodeOptions) *webmStream {
+	return newWebMStream(ctx, func(ctx context.Context, w io.Writer) error {
+		reader, writer := io.Pipe()
+
+		produced := make(chan error, 1)
+		go func() {
+			err := produce(ctx, writer)
+			// Report the error before ffmpeg reads the end of the stream
+			produced <- err
+			writer.CloseWithError(err)
+		}()
+
+		err := ffmpeg.TranscodeReader(ctx, reader, w, options)
+		select {
+		case produceErr := <-produced:
+			// A failed producer fails the transcoding, so prefer its error
+			if produceErr != nil {
+				return produceErr
+			}
+			return err
+		default:
+		}
+
+		// Make sure the producer stops once ffmpeg does
+		reader.Close()
+		<-produced
+		return err
+	})
+}
+
+// ReadFrame implements Stream.
+func (s *webmStream) ReadFrame() ([]byte, error) {
+	frame, err := s.webm.Read()
+	if err == nil {
+		return frame.Payload, nil
+	}
+
+	// Prefer the producer's error, which caused the read to fail
+	select {
+	case <-s.done:
+		if s.err != nil {
+			return nil, s.err
+		}
+	default:
+	}
+
+	return nil, err
+}
+
+// Close implements Stream.
+func (s *webmStream) Close() error {
+	s.cancel()
+	// Make sure writes fail once reading stops
+	s.reader.Close()
+	<-s.done
+	return nil
+}
+
+var _ Stream = (*oggStream)(nil)
+
+// oggStream is a stream of OPUS frames read from an Ogg container.
+type oggStream struct {
+	closer io.Closer
+	reader *ogg.Reader
+	// pending holds packets already read from reader.
+	pending [][]byte
+	// onTitle is called with the title of each chained stream, if non-nil.
+	onTitle func(title string)
+}
+
+// newOggStream reads the OPUS frames of an Ogg Opus stream. The OpusHead
+// packet is expected to already have been read from reader. Any packets
+// already read are passed as pending.
+func newOggStream(reader *ogg.Reader, closer io.Closer, pending ...[]byte) *oggStream {
+	return &oggStream{
+		closer:  closer,
+		reader:  reader,
+		pending: pending,
+	}
+}
+
+// ReadFrame implements Stream. The headers of chained streams, such as those
+// sent by a radio station as it starts playing another song, are skipped.
+func (s *oggStream) ReadFrame() ([]byte, error) {
+	for {
+		packet, err := s.readPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case bytes.HasPrefix(packet, []byte("OpusHead")):
+			continue
+		case bytes.HasPrefix(packet, []byte("OpusTags")):
+			s.readTitle(packet)
+			continue
+		}
+
+		return packet, nil
+	}
+}
+
+// readPacket returns the next pending packet, or the next packet of reader.
+func (s *oggStream) readPacket() ([]byte, error) {
+	if len(s.pending) > 0 {
+		packet := s.pending[0]
+		s.pending = s.pending[1:]
+		return packet, nil
+	}
+
+	return s.reader.ReadPacket()
+}
+
+// readTitle reports the title of an OpusTags packet.
+func (s *oggStream) readTitle(packet []byte) {
+	if s.onTitle == nil {
+		return
+	}
+
+	metadata, err := tags.ParseOpusTags(packet)
+	if err != nil {
+		slog.Debug("Ignoring invalid opus tags", slog.Any("error", err))
+		return
+	}
+
+	title := metadata.Title
+	if metadata.Artist != "" && title != "" {
+		title = metadata.Artist + " - " + title
+	}
+	s.onTitle(title)
+}
+
+// Close implements Stream.
+func (s *oggStream) Close() error {
+	return s.closer.Close()
+}
@@ -0,0 +1,58 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebMStream(t *testing.T) {
+	// A 1s long OPUS-encoded sine wave in a WebM container
+	testFile, err := os.ReadFile("../webm/test.webm")
+	require.NoError(t, err)
+
+	stream := newWebMStream(context.Background(), func(ctx context.Context, w io.Writer) error {
+		_, err := io.Copy(w, bytes.NewReader(testFile))
+		return err
+	})
+	defer stream.Close()
+
+	frames := 0
+	for {
+		_, err := stream.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		frames++
+	}
+
+	// 1s of 20ms frames, give or take padding added by the encoder
+	assert.InDelta(t, 50, frames, 1)
+}
+
+func TestWebMStreamProducerError(t *testing.T) {
+	expected := errors.New("failed")
+	stream := newWebMStream(context.Background(), func(ctx context.Context, w io.Writer) error {
+		return expected
+	})
+	defer stream.Close()
+
+	_, err := stream.ReadFrame()
+	assert.ErrorIs(t, err, expected)
+}
+
+func TestWebMStreamClose(t *testing.T) {
+	stream := newWebMStream(context.Background(), func(ctx context.Context, w io.Writer) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	assert.NoError(t, stream.Close())
+}
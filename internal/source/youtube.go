@@ -0,0 +1,125 @@
+package source
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/AlexGustafsson/clabbe/internal/state"
+	"github.com/AlexGustafsson/clabbe/internal/youtube"
+	"github.com/AlexGustafsson/clabbe/internal/ytdlp"
+)
+
+var _ Provider = (*YouTube)(nil)
+
+// YouTube provides videos from YouTube.
+type YouTube struct {
+	config func() *state.Config
+	client *youtube.SearchClient
+}
+
+// NewYouTube returns a YouTube provider. Playlists are capped to the limit
+// configured in config.
+func NewYouTube(config func() *state.Config) *YouTube {
+	return &YouTube{
+		config: config,
+		client: youtube.NewSearchClient(),
+	}
+}
+
+// YouTubeTrack returns the track of a YouTube search result.
+func YouTubeTrack(result youtube.SearchResult) Track {
+	return Track{
+		Source:   state.SourceYouTube,
+		URI:      result.ID,
+		Title:    result.Title,
+		Artist:   result.Channel,
+		Duration: result.Duration,
+		Views:    result.Views,
+		Live:     result.Live,
+		Verified: result.ChannelBadge != youtube.ChannelBadgeNone,
+		Official: result.ChannelBadge == youtube.ChannelBadgeArtist,
+	}
+}
+
+// Source implements Provider.
+func (p *YouTube) Source() state.Source {
+	return state.SourceYouTube
+}
+
+// Search implements Provider. Shorts and livestreams are left out as they're
+// rarely songs. Additional pages are searched if the first ones have too few
+// results left.
+func (p *YouTube) Search(ctx context.Context, query string, n int) ([]Track, error) {
+	results, err := p.client.SearchN(ctx, query, n, func(result youtube.SearchResult) bool {
+		return !result.Short && !result.Live
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]Track, len(results))
+	for i, result := range results {
+		tracks[i] = YouTubeTrack(result)
+	}
+	return tracks, nil
+}
+
+// Resolve implements Provider. Links to videos and playlists are handled.
+// Playlists are capped to the configured limit.
+func (p *YouTube) Resolve(ctx context.Context, uri string) ([]Track, bool, error) {
+	u, ok := youtube.ParseURL(uri)
+	if !ok {
+		return nil, false, nil
+	}
+
+	if u.PlaylistID != "" {
+		slog.Debug("Resolving playlist", slog.String("id", u.PlaylistID))
+		results, err := p.client.Playlist(ctx, u.PlaylistID, p.config().PlaylistLimit)
+		if err != nil {
+			return nil, true, err
+		}
+
+		tracks := make([]Track, len(results))
+		for i, result := range results {
+			tracks[i] = YouTubeTrack(result)
+		}
+		return tracks, true, nil
+	}
+
+	slog.Debug("Resolving video", slog.String("id", u.VideoID))
+	track, err := p.Metadata(ctx, u.VideoID)
+	if err == ErrNotFound {
+		return []Track{}, true, nil
+	} else if err != nil {
+		return nil, true, err
+	}
+
+	return []Track{track}, true, nil
+}
+
+// Stream implements Provider. The video's OPUS audio is streamed using
+// yt-dlp. The audio is transcoded using ffmpeg if the volume is to be changed.
+func (p *YouTube) Stream(ctx context.Context, uri string, options *StreamOptions) (Stream, error) {
+	produce := func(ctx context.Context, w io.Writer) error {
+		return ytdlp.Stream(ctx, uri, w)
+	}
+
+	if !options.passthrough() {
+		return newTranscodedStream(ctx, produce, options.transcodeOptions()), nil
+	}
+
+	return newWebMStream(ctx, produce), nil
+}
+
+// Metadata implements Provider.
+func (p *YouTube) Metadata(ctx context.Context, uri string) (Track, error) {
+	result, ok, err := p.client.Lookup(ctx, uri)
+	if err != nil {
+		return Track{}, err
+	} else if !ok {
+		return Track{}, ErrNotFound
+	}
+
+	return YouTubeTrack(result), nil
+}
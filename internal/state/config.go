@@ -2,61 +2,430 @@ package state
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/AlexGustafsson/clabbe/internal/prompt"
 	"github.com/caarlos0/env/v10"
 	"gopkg.in/yaml.v3"
 )
 
+// EnvironmentPrefix is the prefix of all environment variables used to
+// configure the bot.
+const EnvironmentPrefix = "CLABBE_"
+
 type Config struct {
 	DiscordBotToken string        `yaml:"discordBotToken,omitempty" env:"DISCORD_BOT_TOKEN"`
-	Ollama          *OllamaConfig `yaml:"ollama,omitempty"`
+	Ollama          *OllamaConfig `yaml:"ollama,omitempty" envPrefix:"OLLAMA_"`
+
+	ExtrapolateWhenEmpty  bool `yaml:"extrapolateWhenEmpty" env:"EXTRAPOLATE_WHEN_EMPTY"`
+	ExtrapolationLookback int  `yaml:"extrapolationLookback" env:"EXTRAPOLATION_LOOKBACK"`
+
+	// PlaylistLimit is the maximum number of songs to queue from a playlist.
+	PlaylistLimit int `yaml:"playlistLimit" env:"PLAYLIST_LIMIT"`
+
+	// MaxDuration is the maximum duration of songs that may be queued. Zero
+	// for no limit.
+	MaxDuration time.Duration `yaml:"maxDuration" env:"MAX_DURATION"`
 
-	ExtrapolateWhenEmpty  bool `yaml:"extrapolateWhenEmpty"`
-	ExtrapolationLookback int  `yaml:"extrapolationLookback"`
+	// IdleTimeout is the time to stay in a voice channel without listeners
+	// before leaving. Zero to never leave.
+	IdleTimeout time.Duration `yaml:"idleTimeout" env:"IDLE_TIMEOUT"`
 
-	Prometheus *PrometheusConfig `yaml:"prometheus,omitempty"`
+	// Sources configures additional sources of music. YouTube is always
+	// available.
+	Sources []SourceConfig `yaml:"sources,omitempty"`
 
-	Prompt       string `yaml:"-"`
+	// Stations holds radio stations that can be played by name.
+	Stations []StationConfig `yaml:"stations,omitempty"`
+
+	// Scoring configures how search results are scored in order to pick the
+	// best match of a search.
+	Scoring ScoringConfig `yaml:"scoring" envPrefix:"SCORING_"`
+
+	Prometheus *PrometheusConfig `yaml:"prometheus,omitempty" envPrefix:"PROMETHEUS_"`
+
+	// Prompt is the template used to request songs from the LLM. Read from
+	// SongSuggestionPromptFile. Empty to use the default template.
+	Prompt string `yaml:"-"`
+	// ThemesPrompt is the template used to request themes from the LLM. Read
+	// from ThemeSuggestionPromptFile. Empty to use the default template.
 	ThemesPrompt string `yaml:"-"`
 
-	LogLevel slog.Level `yaml:"logLevel"`
+	LogLevel slog.Level `yaml:"logLevel" env:"LOG_LEVEL"`
 }
 
 type PrometheusConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	Port    uint16 `yaml:"port"`
+	Enabled bool   `yaml:"enabled" env:"ENABLED"`
+	Port    uint16 `yaml:"port" env:"PORT"`
+}
+
+// SourceConfig configures a source of music.
+type SourceConfig struct {
+	// Type is the type of source, "local".
+	Type string `yaml:"type"`
+	// Path is the path of the music library of a local source.
+	Path string `yaml:"path,omitempty"`
+	// Rescan is the interval at which to rescan the music library of a local
+	// source. Zero to only scan it on startup.
+	Rescan time.Duration `yaml:"rescan,omitempty"`
+}
+
+// StationConfig configures a radio station.
+type StationConfig struct {
+	// Name is the name used to play the station.
+	Name string `yaml:"name"`
+	// URL is the URL of the station's HTTP stream.
+	URL string `yaml:"url"`
+}
+
+// ScoringConfig holds the weights used to score search results. The highest
+// scored of the top results is picked. Set all weights to 0 to always pick the
+// top result.
+type ScoringConfig struct {
+	// Topic is added for songs uploaded by YouTube's auto-generated "Topic"
+	// channels, which hold the songs as released.
+	Topic int `yaml:"topic" env:"TOPIC"`
+	// Official is added for songs uploaded by official artist channels.
+	Official int `yaml:"official" env:"OFFICIAL"`
+	// Verified is added for songs uploaded by other verified channels.
+	Verified int `yaml:"verified" env:"VERIFIED"`
+	// OfficialTitle is added for songs with "official" in their title.
+	OfficialTitle int `yaml:"officialTitle" env:"OFFICIAL_TITLE"`
+	// UnwantedVersion is subtracted for live versions, covers and the like,
+	// unless searched for.
+	UnwantedVersion int `yaml:"unwantedVersion" env:"UNWANTED_VERSION"`
 }
 
 type OllamaConfig struct {
-	Endpoint string `yaml:"endpoint"`
-	Model    string `yaml:"model"`
+	Endpoint string `yaml:"endpoint" env:"ENDPOINT"`
+	Model    string `yaml:"model" env:"MODEL"`
 }
 
+// ConfigOverride overrides values of a config, such as from command line
+// flags.
+type ConfigOverride func(*Config)
+
 // DefaultConfig returns the default config.
 func DefaultConfig() *Config {
 	return &Config{
 		ExtrapolateWhenEmpty:  true,
 		ExtrapolationLookback: 10,
 
+		PlaylistLimit: 50,
+		MaxDuration:   time.Hour,
+
+		IdleTimeout: 5 * time.Minute,
+
+		Scoring: ScoringConfig{
+			Topic:           3,
+			Official:        3,
+			Verified:        1,
+			OfficialTitle:   1,
+			UnwantedVersion: 2,
+		},
+
 		Prometheus: &PrometheusConfig{
 			Enabled: false,
 			Port:    8080,
 		},
 
-		Prompt:       DefaultPrompt,
-		ThemesPrompt: GenerateThemesPrompt,
-
 		LogLevel: slog.LevelInfo,
 	}
 }
 
+// FieldError describes an invalid config field.
+type FieldError struct {
+	// Field is the path of the field, as named in the config file.
+	Field string
+	Err   error
+}
+
+// Error implements error.
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Validate validates the config. Returns all invalid fields as FieldErrors
+// joined using errors.Join.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.DiscordBotToken == "" {
+		errs = append(errs, FieldError{Field: "discordBotToken", Err: errors.New("required")})
+	}
+
+	if c.Ollama != nil {
+		if c.Ollama.Endpoint == "" {
+			errs = append(errs, FieldError{Field: "ollama.endpoint", Err: errors.New("required")})
+		} else if u, err := url.Parse(c.Ollama.Endpoint); err != nil {
+			errs = append(errs, FieldError{Field: "ollama.endpoint", Err: err})
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			errs = append(errs, FieldError{Field: "ollama.endpoint", Err: errors.New("expected http or https URL")})
+		}
+
+		if c.Ollama.Model == "" {
+			errs = append(errs, FieldError{Field: "ollama.model", Err: errors.New("required")})
+		}
+	}
+
+	if c.ExtrapolationLookback < 0 {
+		errs = append(errs, FieldError{Field: "extrapolationLookback", Err: errors.New("must not be negative")})
+	}
+
+	if c.PlaylistLimit < 1 {
+		errs = append(errs, FieldError{Field: "playlistLimit", Err: errors.New("must be positive")})
+	}
+
+	if c.MaxDuration < 0 {
+		errs = append(errs, FieldError{Field: "maxDuration", Err: errors.New("must not be negative")})
+	}
+
+	if c.IdleTimeout < 0 {
+		errs = append(errs, FieldError{Field: "idleTimeout", Err: errors.New("must not be negative")})
+	}
+
+	for i, source := range c.Sources {
+		switch source.Type {
+		case "":
+			errs = append(errs, FieldError{Field: fmt.Sprintf("sources[%d].type", i), Err: errors.New("required")})
+		case "local":
+			if source.Path == "" {
+				errs = append(errs, FieldError{Field: fmt.Sprintf("sources[%d].path", i), Err: errors.New("required")})
+			}
+
+			if source.Rescan < 0 {
+				errs = append(errs, FieldError{Field: fmt.Sprintf("sources[%d].rescan", i), Err: errors.New("must not be negative")})
+			}
+		default:
+			errs = append(errs, FieldError{Field: fmt.Sprintf("sources[%d].type", i), Err: fmt.Errorf("unsupported source type %q", source.Type)})
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, station := range c.Stations {
+		if station.Name == "" {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("stations[%d].name", i), Err: errors.New("required")})
+		} else if names[strings.ToLower(station.Name)] {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("stations[%d].name", i), Err: errors.New("must be unique")})
+		}
+		names[strings.ToLower(station.Name)] = true
+
+		if station.URL == "" {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("stations[%d].url", i), Err: errors.New("required")})
+		} else if u, err := url.Parse(station.URL); err != nil {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("stations[%d].url", i), Err: err})
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("stations[%d].url", i), Err: errors.New("expected http or https URL")})
+		}
+	}
+
+	if err := prompt.Validate(c.Prompt); err != nil {
+		errs = append(errs, FieldError{Field: SongSuggestionPromptFile, Err: err})
+	}
+
+	if err := prompt.Validate(c.ThemesPrompt); err != nil {
+		errs = append(errs, FieldError{Field: ThemeSuggestionPromptFile, Err: err})
+	}
+
+	if c.Prometheus != nil && c.Prometheus.Enabled && c.Prometheus.Port == 0 {
+		errs = append(errs, FieldError{Field: "prometheus.port", Err: errors.New("must not be 0")})
+	}
+
+	return errors.Join(errs...)
+}
+
+// ConfigChanges describes the result of applying a new config.
+type ConfigChanges struct {
+	// Applied holds the fields that were changed and applied.
+	Applied []string
+	// RequiresRestart holds the fields that were changed, but which require a
+	// restart to take effect.
+	RequiresRestart []string
+}
+
+// Apply returns a copy of c with the values of next that can safely be changed
+// at runtime. Fields which cannot be changed at runtime are left untouched and
+// reported in the returned changes. Neither c nor next is modified, so that c
+// can still be read while the copy is being made.
+func (c *Config) Apply(next *Config) (*Config, ConfigChanges) {
+	var changes ConfigChanges
+	applied := *c
+
+	if c.LogLevel != next.LogLevel {
+		applied.LogLevel = next.LogLevel
+		changes.Applied = append(changes.Applied, "logLevel")
+	}
+
+	if c.ExtrapolateWhenEmpty != next.ExtrapolateWhenEmpty {
+		applied.ExtrapolateWhenEmpty = next.ExtrapolateWhenEmpty
+		changes.Applied = append(changes.Applied, "extrapolateWhenEmpty")
+	}
+
+	if c.ExtrapolationLookback != next.ExtrapolationLookback {
+		applied.ExtrapolationLookback = next.ExtrapolationLookback
+		changes.Applied = append(changes.Applied, "extrapolationLookback")
+	}
+
+	if c.PlaylistLimit != next.PlaylistLimit {
+		applied.PlaylistLimit = next.PlaylistLimit
+		changes.Applied = append(changes.Applied, "playlistLimit")
+	}
+
+	if c.MaxDuration != next.MaxDuration {
+		applied.MaxDuration = next.MaxDuration
+		changes.Applied = append(changes.Applied, "maxDuration")
+	}
+
+	if c.IdleTimeout != next.IdleTimeout {
+		applied.IdleTimeout = next.IdleTimeout
+		changes.Applied = append(changes.Applied, "idleTimeout")
+	}
+
+	if !slices.Equal(c.Stations, next.Stations) {
+		applied.Stations = slices.Clone(next.Stations)
+		changes.Applied = append(changes.Applied, "stations")
+	}
+
+	if c.Scoring != next.Scoring {
+		applied.Scoring = next.Scoring
+		changes.Applied = append(changes.Applied, "scoring")
+	}
+
+	if c.Prompt != next.Prompt {
+		applied.Prompt = next.Prompt
+		changes.Applied = append(changes.Applied, "prompt")
+	}
+
+	if c.ThemesPrompt != next.ThemesPrompt {
+		applied.ThemesPrompt = next.ThemesPrompt
+		changes.Applied = append(changes.Applied, "themesPrompt")
+	}
+
+	// Commands depend on whether or not an LLM is available, so enabling or
+	// disabling Ollama requires a restart. Changing the endpoint or model of an
+	// already configured client is fine
+	if (c.Ollama == nil) != (next.Ollama == nil) {
+		changes.RequiresRestart = append(changes.RequiresRestart, "ollama")
+	} else if c.Ollama != nil && *c.Ollama != *next.Ollama {
+		ollama := *next.Ollama
+		applied.Ollama = &ollama
+		changes.Applied = append(changes.Applied, "ollama")
+	}
+
+	if c.DiscordBotToken != next.DiscordBotToken {
+		changes.RequiresRestart = append(changes.RequiresRestart, "discordBotToken")
+	}
+
+	if !slices.Equal(c.Sources, next.Sources) {
+		changes.RequiresRestart = append(changes.RequiresRestart, "sources")
+	}
+
+	if (c.Prometheus == nil) != (next.Prometheus == nil) || (c.Prometheus != nil && *c.Prometheus != *next.Prometheus) {
+		changes.RequiresRestart = append(changes.RequiresRestart, "prometheus")
+	}
+
+	return &applied, changes
+}
+
+// Station returns the configured station with the name, ignoring case.
+func (c *Config) Station(name string) (StationConfig, bool) {
+	for _, station := range c.Stations {
+		if strings.EqualFold(station.Name, name) {
+			return station, true
+		}
+	}
+
+	return StationConfig{}, false
+}
+
 // PopulateFromEnvironment populates the config with values from environment
-// variables.
+// variables. All variables are prefixed with EnvironmentPrefix.
+//
+// For any variable, such as CLABBE_DISCORD_BOT_TOKEN, a variable with the
+// suffix _FILE may be used to instead read the value from a file. This is
+// useful for secrets mounted as files. The variable takes precedence over the
+// file.
+//
+// For backwards compatibility, DISCORD_BOT_TOKEN is used if
+// CLABBE_DISCORD_BOT_TOKEN is not set.
 func (c *Config) PopulateFromEnvironment() error {
-	return env.Parse(c)
+	environment, err := readEnvironment(os.Environ())
+	if err != nil {
+		return err
+	}
+
+	if _, ok := environment[EnvironmentPrefix+"DISCORD_BOT_TOKEN"]; !ok {
+		if value, ok := environment["DISCORD_BOT_TOKEN"]; ok {
+			environment[EnvironmentPrefix+"DISCORD_BOT_TOKEN"] = value
+		}
+	}
+
+	// Optional sections are only populated if they are already set. Make sure
+	// they exist and remove them again if they were not populated
+	if c.Ollama == nil {
+		c.Ollama = &OllamaConfig{}
+		defer func() {
+			if *c.Ollama == (OllamaConfig{}) {
+				c.Ollama = nil
+			}
+		}()
+	}
+
+	if c.Prometheus == nil {
+		c.Prometheus = &PrometheusConfig{}
+		defer func() {
+			if *c.Prometheus == (PrometheusConfig{}) {
+				c.Prometheus = nil
+			}
+		}()
+	}
+
+	return env.ParseWithOptions(c, env.Options{
+		Environment: environment,
+		Prefix:      EnvironmentPrefix,
+	})
+}
+
+// readEnvironment parses environment variables in the form of key=value,
+// resolving any _FILE variables.
+func readEnvironment(variables []string) (map[string]string, error) {
+	environment := make(map[string]string)
+	files := make(map[string]string)
+	for _, variable := range variables {
+		key, value, _ := strings.Cut(variable, "=")
+		if name, ok := strings.CutSuffix(key, "_FILE"); ok && strings.HasPrefix(key, EnvironmentPrefix) {
+			files[name] = value
+		} else {
+			environment[key] = value
+		}
+	}
+
+	for key, path := range files {
+		if _, ok := environment[key]; ok {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s_FILE: %w", key, err)
+		}
+
+		environment[key] = strings.TrimRight(string(content), "\r\n")
+	}
+
+	return environment, nil
 }
 
 // CreateConfigIfNotExists makes sure that a config file exists. If it doesn't,
@@ -86,6 +455,10 @@ func ReadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.readPrompts(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
 	return config, err
 }
 
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig()
+	config.DiscordBotToken = "token"
+	require.NoError(t, config.Validate())
+
+	config.ExtrapolationLookback = -1
+	config.IdleTimeout = -time.Second
+	config.PlaylistLimit = 0
+	config.MaxDuration = -time.Minute
+	config.Prometheus.Enabled = true
+	config.Prometheus.Port = 0
+	config.Ollama = &OllamaConfig{
+		Endpoint: "localhost:11434",
+	}
+	config.Sources = []SourceConfig{
+		{Type: "local"},
+		{Type: "unknown"},
+		{},
+	}
+	config.Stations = []StationConfig{
+		{Name: "Jazz", URL: "https://example.com/jazz.ogg"},
+		{Name: "jazz", URL: "ftp://example.com/jazz.mp3"},
+		{URL: "https://example.com/rock.mp3"},
+	}
+	config.Prompt = "{{ range first .history 5 }}"
+	config.ThemesPrompt = "{{ unknown }}"
+
+	err := config.Validate()
+	require.Error(t, err)
+
+	fields := make([]string, 0)
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		fields = append(fields, err.(FieldError).Field)
+	}
+	assert.ElementsMatch(t, []string{"ollama.endpoint", "ollama.model", "extrapolationLookback", "playlistLimit", "maxDuration", "idleTimeout", "sources[0].path", "sources[1].type", "sources[2].type", "stations[1].name", "stations[1].url", "stations[2].name", SongSuggestionPromptFile, ThemeSuggestionPromptFile, "prometheus.port"}, fields)
+}
+
+func TestConfigApply(t *testing.T) {
+	config := DefaultConfig()
+
+	next := DefaultConfig()
+	next.LogLevel = slog.LevelDebug
+	next.ExtrapolationLookback = 5
+	next.DiscordBotToken = "token"
+	next.Sources = []SourceConfig{{Type: "local", Path: "/music"}}
+	next.Stations = []StationConfig{{Name: "Jazz", URL: "https://example.com/jazz.ogg"}}
+
+	applied, changes := config.Apply(next)
+	assert.ElementsMatch(t, []string{"logLevel", "extrapolationLookback", "stations"}, changes.Applied)
+	assert.ElementsMatch(t, []string{"discordBotToken", "sources"}, changes.RequiresRestart)
+
+	assert.Equal(t, slog.LevelDebug, applied.LogLevel)
+	assert.Equal(t, 5, applied.ExtrapolationLookback)
+	assert.Equal(t, "", applied.DiscordBotToken)
+	assert.Empty(t, applied.Sources)
+
+	// The current config is left untouched
+	assert.Equal(t, DefaultConfig(), config)
+
+	station, ok := applied.Station("jazz")
+	require.True(t, ok)
+	assert.Equal(t, "https://example.com/jazz.ogg", station.URL)
+}
+
+func TestPopulateFromEnvironment(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(secret, []byte("secret\n"), 0600))
+
+	t.Setenv("CLABBE_DISCORD_BOT_TOKEN_FILE", secret)
+	t.Setenv("CLABBE_OLLAMA_ENDPOINT", "http://localhost:11434")
+	t.Setenv("CLABBE_OLLAMA_MODEL", "llama3")
+	t.Setenv("CLABBE_PROMETHEUS_PORT", "9090")
+	t.Setenv("CLABBE_EXTRAPOLATION_LOOKBACK", "5")
+	t.Setenv("CLABBE_SCORING_TOPIC", "5")
+	t.Setenv("CLABBE_LOG_LEVEL", "debug")
+	t.Setenv("CLABBE_IDLE_TIMEOUT", "1m30s")
+	t.Setenv("CLABBE_MAX_DURATION", "20m")
+
+	config := DefaultConfig()
+	require.NoError(t, config.PopulateFromEnvironment())
+
+	assert.Equal(t, "secret", config.DiscordBotToken)
+	assert.Equal(t, &OllamaConfig{Endpoint: "http://localhost:11434", Model: "llama3"}, config.Ollama)
+	assert.Equal(t, uint16(9090), config.Prometheus.Port)
+	assert.Equal(t, 5, config.ExtrapolationLookback)
+	assert.Equal(t, 5, config.Scoring.Topic)
+	assert.Equal(t, 3, config.Scoring.Official)
+	assert.Equal(t, slog.LevelDebug, config.LogLevel)
+	assert.Equal(t, 90*time.Second, config.IdleTimeout)
+	assert.Equal(t, 20*time.Minute, config.MaxDuration)
+}
+
+func TestPopulateFromEnvironmentLegacy(t *testing.T) {
+	t.Setenv("DISCORD_BOT_TOKEN", "legacy")
+
+	config := DefaultConfig()
+	require.NoError(t, config.PopulateFromEnvironment())
+
+	assert.Equal(t, "legacy", config.DiscordBotToken)
+	assert.Nil(t, config.Ollama)
+}
@@ -0,0 +1,350 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/youtube"
+)
+
+var (
+	ErrUnsupportedPlaylistFormat = errors.New("unsupported playlist format")
+)
+
+// PlaylistFormat is a file format used to import and export playlists.
+type PlaylistFormat string
+
+const (
+	// PlaylistFormatJSON is the native playlist format, as stored on disk.
+	PlaylistFormatJSON PlaylistFormat = "json"
+	// PlaylistFormatM3U is the UTF-8 variant of the extended M3U format.
+	PlaylistFormatM3U PlaylistFormat = "m3u8"
+	// PlaylistFormatXSPF is the XML Shareable Playlist Format.
+	PlaylistFormatXSPF PlaylistFormat = "xspf"
+)
+
+// PlaylistFormatFromFilename identifies a playlist format from a file's
+// extension.
+func PlaylistFormatFromFilename(name string) (PlaylistFormat, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return PlaylistFormatJSON, true
+	case ".m3u", ".m3u8":
+		return PlaylistFormatM3U, true
+	case ".xspf":
+		return PlaylistFormatXSPF, true
+	default:
+		return "", false
+	}
+}
+
+// ContentType returns the MIME type of the format.
+func (f PlaylistFormat) ContentType() string {
+	switch f {
+	case PlaylistFormatJSON:
+		return "application/json"
+	case PlaylistFormatM3U:
+		return "audio/x-mpegurl"
+	case PlaylistFormatXSPF:
+		return "application/xspf+xml"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// EntryURL returns a URL that can be used to access the entry outside of the
+// bot.
+func EntryURL(entry PlaylistEntry) (string, error) {
+	switch entry.Source {
+	case SourceYouTube:
+		u := url.URL{
+			Scheme:   "https",
+			Host:     "www.youtube.com",
+			Path:     "/watch",
+			RawQuery: url.Values{"v": []string{entry.URI}}.Encode(),
+		}
+		return u.String(), nil
+	case SourceRadio:
+		return entry.URI, nil
+	default:
+		// Media of sites supported by yt-dlp are referred to by their URLs
+		if u, err := url.Parse(entry.URI); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			return entry.URI, nil
+		}
+		return "", fmt.Errorf("unsupported source: %s", entry.Source)
+	}
+}
+
+// ParseEntryURL parses a URL referring to a single YouTube video, such as one
+// created by EntryURL, returning the source and source-specific URI it refers
+// to. URLs of other sources can't be told apart by the URL alone, see
+// importedEntry.
+func ParseEntryURL(location string) (Source, string, bool) {
+	if u, ok := youtube.ParseURL(location); ok && u.VideoID != "" {
+		return SourceYouTube, u.VideoID, true
+	}
+
+	return "", "", false
+}
+
+// ExportPlaylist writes the entries to w using the specified format, returning
+// the number of exported entries. Entries that cannot be represented in the
+// format are skipped.
+func ExportPlaylist(w io.Writer, entries []PlaylistEntry, format PlaylistFormat) (int, error) {
+	switch format {
+	case PlaylistFormatJSON:
+		playlist := &Playlist{entries: entries}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(playlist); err != nil {
+			return 0, err
+		}
+		return len(entries), nil
+	case PlaylistFormatM3U:
+		return exportM3U(w, entries)
+	case PlaylistFormatXSPF:
+		return exportXSPF(w, entries)
+	default:
+		return 0, ErrUnsupportedPlaylistFormat
+	}
+}
+
+// ImportPlaylist reads entries from r using the specified format.
+// Entries referring to unsupported sources are skipped.
+func ImportPlaylist(r io.Reader, format PlaylistFormat) ([]PlaylistEntry, error) {
+	switch format {
+	case PlaylistFormatJSON:
+		return importJSON(r)
+	case PlaylistFormatM3U:
+		return importM3U(r)
+	case PlaylistFormatXSPF:
+		return importXSPF(r)
+	default:
+		return nil, ErrUnsupportedPlaylistFormat
+	}
+}
+
+func importJSON(r io.Reader) ([]PlaylistEntry, error) {
+	var playlist Playlist
+	if err := json.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, err
+	}
+
+	entries := make([]PlaylistEntry, 0, len(playlist.entries))
+	for _, entry := range playlist.entries {
+		if isPlayableEntry(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// isPlayableEntry returns whether or not an entry read from an untrusted
+// source refers to something the bot can play. Entries of sites supported by
+// yt-dlp must refer to a http(s) URL, so that they are never mistaken for
+// options.
+func isPlayableEntry(entry PlaylistEntry) bool {
+	switch entry.Source {
+	case "":
+		return false
+	case SourceYouTube:
+		return youtube.IsVideoID(entry.URI)
+	case SourceLocal:
+		return entry.URI != ""
+	default:
+		_, err := EntryURL(entry)
+		return err == nil
+	}
+}
+
+// m3uSourceDirective is the M3U directive holding the source of an entry which
+// isn't a YouTube video. Players treat it as a comment.
+const m3uSourceDirective = "#CLABBE-SOURCE:"
+
+// xspfSourceRel identifies the XSPF meta element holding the source of a track
+// which isn't a YouTube video.
+const xspfSourceRel = "https://github.com/AlexGustafsson/clabbe#source"
+
+// importedEntry returns the entry referred to by the location of an entry of
+// an imported playlist. YouTube videos are identified by their URL. Other
+// entries are identified using the source written when exporting them. URLs
+// without a source, such as those of playlists made elsewhere, are played using
+// yt-dlp.
+func importedEntry(location string, title string, source Source) (PlaylistEntry, bool) {
+	entry := PlaylistEntry{
+		Time:  time.Now(),
+		Title: title,
+	}
+	if entry.Title == "" {
+		entry.Title = location
+	}
+
+	if source, uri, ok := ParseEntryURL(location); ok {
+		entry.Source = source
+		entry.URI = uri
+		return entry, true
+	}
+
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return PlaylistEntry{}, false
+	}
+
+	switch source {
+	case SourceYouTube, SourceLocal:
+		return PlaylistEntry{}, false
+	case "":
+		entry.Source = SourceGeneric
+	default:
+		// Radio stations or media of sites supported by yt-dlp, named after the
+		// site's extractor
+		entry.Source = source
+	}
+
+	entry.URI = location
+	return entry, true
+}
+
+func exportM3U(w io.Writer, entries []PlaylistEntry) (int, error) {
+	exported := 0
+	writer := bufio.NewWriter(w)
+	writer.WriteString("#EXTM3U\n")
+	for _, entry := range entries {
+		location, err := EntryURL(entry)
+		if err != nil {
+			continue
+		}
+
+		// Titles may not contain newlines as they would break the format
+		title := strings.ReplaceAll(entry.Title, "\n", " ")
+		fmt.Fprintf(writer, "#EXTINF:-1,%s\n", title)
+		if entry.Source != SourceYouTube {
+			fmt.Fprintf(writer, "%s%s\n", m3uSourceDirective, entry.Source)
+		}
+		fmt.Fprintf(writer, "%s\n", location)
+		exported++
+	}
+	return exported, writer.Flush()
+}
+
+func importM3U(r io.Reader) ([]PlaylistEntry, error) {
+	entries := make([]PlaylistEntry, 0)
+
+	title := ""
+	var source Source
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			// #EXTINF:<duration>,<title>
+			if info, ok := strings.CutPrefix(line, "#EXTINF:"); ok {
+				_, title, _ = strings.Cut(info, ",")
+			} else if value, ok := strings.CutPrefix(line, m3uSourceDirective); ok {
+				source = Source(value)
+			}
+			continue
+		}
+
+		if entry, ok := importedEntry(line, title, source); ok {
+			entries = append(entries, entry)
+		}
+		title = ""
+		source = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SEE: https://www.xspf.org/spec
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string     `xml:"location"`
+	Title    string     `xml:"title,omitempty"`
+	Meta     []xspfMeta `xml:"meta"`
+}
+
+type xspfMeta struct {
+	Rel     string `xml:"rel,attr"`
+	Content string `xml:",chardata"`
+}
+
+// source returns the source written by exportXSPF, if any.
+func (t xspfTrack) source() Source {
+	for _, meta := range t.Meta {
+		if meta.Rel == xspfSourceRel {
+			return Source(meta.Content)
+		}
+	}
+	return ""
+}
+
+func exportXSPF(w io.Writer, entries []PlaylistEntry) (int, error) {
+	playlist := xspfPlaylist{
+		Version: "1",
+		Tracks:  make([]xspfTrack, 0),
+	}
+	for _, entry := range entries {
+		location, err := EntryURL(entry)
+		if err != nil {
+			continue
+		}
+
+		track := xspfTrack{
+			Location: location,
+			Title:    entry.Title,
+		}
+		if entry.Source != SourceYouTube {
+			track.Meta = []xspfMeta{{Rel: xspfSourceRel, Content: string(entry.Source)}}
+		}
+
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return 0, err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(&playlist); err != nil {
+		return 0, err
+	}
+	return len(playlist.Tracks), encoder.Close()
+}
+
+func importXSPF(r io.Reader) ([]PlaylistEntry, error) {
+	var playlist xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, err
+	}
+
+	entries := make([]PlaylistEntry, 0)
+	for _, track := range playlist.Tracks {
+		if entry, ok := importedEntry(track.Location, track.Title, track.source()); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
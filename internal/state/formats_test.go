@@ -0,0 +1,200 @@
+package state
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylistFormats(t *testing.T) {
+	entries := []PlaylistEntry{
+		{
+			Title:  "Rick Astley - Never Gonna Give You Up",
+			Source: SourceYouTube,
+			URI:    "dQw4w9WgXcQ",
+		},
+		{
+			Title:  "youtube-dl test video \"'/\\ä↭𝕐",
+			Source: SourceYouTube,
+			URI:    "BaW_jenozKc",
+		},
+	}
+
+	for _, format := range []PlaylistFormat{PlaylistFormatJSON, PlaylistFormatM3U, PlaylistFormatXSPF} {
+		t.Run(string(format), func(t *testing.T) {
+			var buffer bytes.Buffer
+			exported, err := ExportPlaylist(&buffer, entries, format)
+			require.NoError(t, err)
+			assert.Equal(t, len(entries), exported)
+
+			imported, err := ImportPlaylist(&buffer, format)
+			require.NoError(t, err)
+			require.Len(t, imported, len(entries))
+
+			for i, entry := range entries {
+				assert.Equal(t, entry.Title, imported[i].Title)
+				assert.Equal(t, entry.Source, imported[i].Source)
+				assert.Equal(t, entry.URI, imported[i].URI)
+			}
+		})
+	}
+}
+
+func TestPlaylistFormatsMixedSources(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Entries []PlaylistEntry
+	}{
+		{
+			Name: "mixed",
+			Entries: []PlaylistEntry{
+				{Title: "Song", Source: SourceYouTube, URI: "dQw4w9WgXcQ"},
+				{Title: "Station", Source: SourceRadio, URI: "https://example.com/stream"},
+				{Title: "Other site", Source: "soundcloud", URI: "https://soundcloud.com/artist/song"},
+				{Title: "File", Source: SourceGeneric, URI: "https://example.com/song.mp3"},
+			},
+		},
+		{
+			Name: "radio only",
+			Entries: []PlaylistEntry{
+				{Title: "Station", Source: SourceRadio, URI: "https://example.com/stream"},
+				{Title: "Other station", Source: SourceRadio, URI: "http://example.com:8000/live.ogg"},
+			},
+		},
+	}
+
+	for _, format := range []PlaylistFormat{PlaylistFormatM3U, PlaylistFormatXSPF} {
+		for _, testCase := range testCases {
+			t.Run(string(format)+" "+testCase.Name, func(t *testing.T) {
+				var buffer bytes.Buffer
+				exported, err := ExportPlaylist(&buffer, testCase.Entries, format)
+				require.NoError(t, err)
+				assert.Equal(t, len(testCase.Entries), exported)
+
+				imported, err := ImportPlaylist(&buffer, format)
+				require.NoError(t, err)
+				require.Len(t, imported, len(testCase.Entries))
+
+				for i, entry := range testCase.Entries {
+					assert.Equal(t, entry.Title, imported[i].Title)
+					assert.Equal(t, entry.Source, imported[i].Source)
+					assert.Equal(t, entry.URI, imported[i].URI)
+				}
+			})
+		}
+	}
+}
+
+func TestImportPlaylistM3USources(t *testing.T) {
+	data := `#EXTM3U
+#EXTINF:-1,Station
+#CLABBE-SOURCE:radio
+https://example.com/stream
+#EXTINF:-1,Not a URL
+#CLABBE-SOURCE:radio
+--exec=touch /tmp/x
+#EXTINF:-1,Song
+https://www.youtube.com/watch?v=dQw4w9WgXcQ
+#EXTINF:-1,Made elsewhere
+https://example.com/song.mp3
+#EXTINF:-1,Not YouTube
+#CLABBE-SOURCE:youtube
+https://example.com/video
+#EXTINF:-1,Not local
+#CLABBE-SOURCE:local
+https://example.com/song.flac
+`
+
+	imported, err := ImportPlaylist(strings.NewReader(data), PlaylistFormatM3U)
+	require.NoError(t, err)
+
+	titles := make([]string, len(imported))
+	for i, entry := range imported {
+		titles[i] = entry.Title
+	}
+	assert.Equal(t, []string{"Station", "Song", "Made elsewhere"}, titles)
+	assert.Equal(t, SourceGeneric, imported[2].Source)
+	assert.Equal(t, "https://example.com/song.mp3", imported[2].URI)
+}
+
+func TestExportPlaylistSkipped(t *testing.T) {
+	entries := []PlaylistEntry{
+		{Title: "Song", Source: SourceYouTube, URI: "dQw4w9WgXcQ"},
+		{Title: "Local song", Source: SourceLocal, URI: "Artist/Album/01 Song.flac"},
+	}
+
+	var buffer bytes.Buffer
+	exported, err := ExportPlaylist(&buffer, entries, PlaylistFormatM3U)
+	require.NoError(t, err)
+	assert.Equal(t, 1, exported)
+}
+
+func TestImportPlaylistJSON(t *testing.T) {
+	data := `{"version": "1", "entries": [
+  {"title": "Song", "source": "youtube", "uri": "dQw4w9WgXcQ"},
+  {"title": "Invalid id", "source": "youtube", "uri": "--batch-file=/etc/passwd"},
+  {"title": "Local song", "source": "local", "uri": "Artist/Album/01 Song.flac"},
+  {"title": "Empty local", "source": "local", "uri": ""},
+  {"title": "Station", "source": "radio", "uri": "https://example.com/stream"},
+  {"title": "Other site", "source": "soundcloud", "uri": "https://soundcloud.com/artist/song"},
+  {"title": "Option", "source": "soundcloud", "uri": "--exec=touch /tmp/x"},
+  {"title": "No source", "source": "", "uri": "https://example.com/song"}
+]}`
+
+	imported, err := ImportPlaylist(strings.NewReader(data), PlaylistFormatJSON)
+	require.NoError(t, err)
+
+	titles := make([]string, len(imported))
+	for i, entry := range imported {
+		titles[i] = entry.Title
+	}
+	assert.Equal(t, []string{"Song", "Local song", "Station", "Other site"}, titles)
+}
+
+func TestParseEntryURL(t *testing.T) {
+	testCases := []struct {
+		URL      string
+		Expected string
+	}{
+		{
+			URL:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			Expected: "dQw4w9WgXcQ",
+		},
+		{
+			URL:      "https://youtu.be/dQw4w9WgXcQ",
+			Expected: "dQw4w9WgXcQ",
+		},
+		{
+			URL:      "https://music.youtube.com/watch?v=dQw4w9WgXcQ&list=RDAMVM",
+			Expected: "dQw4w9WgXcQ",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.URL, func(t *testing.T) {
+			source, uri, ok := ParseEntryURL(testCase.URL)
+			require.True(t, ok)
+			assert.Equal(t, SourceYouTube, source)
+			assert.Equal(t, testCase.Expected, uri)
+		})
+	}
+
+	_, _, ok := ParseEntryURL("https://example.com/song.mp3")
+	assert.False(t, ok)
+}
+
+func TestEntryURL(t *testing.T) {
+	location, err := EntryURL(PlaylistEntry{Source: SourceYouTube, URI: "dQw4w9WgXcQ"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", location)
+
+	location, err = EntryURL(PlaylistEntry{Source: "soundcloud", URI: "https://soundcloud.com/artist/song"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://soundcloud.com/artist/song", location)
+
+	_, err = EntryURL(PlaylistEntry{Source: SourceLocal, URI: "Artist/Album/01 Song.flac"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// GuildSettings holds settings for a single guild.
+type GuildSettings struct {
+	// Volume is the default playback volume in percent.
+	// NOTE: Opus streams are usually sent to Discord as-is. Any other volume
+	// than 100 requires streams to be transcoded, which costs CPU time.
+	Volume int `json:"volume"`
+	// Extrapolate controls whether or not to fill the queue once it runs dry.
+	// A nil value uses the config's default.
+	Extrapolate *bool `json:"extrapolate,omitempty"`
+	// ExtrapolationLookback is the number of songs to base extrapolation on.
+	// A zero value uses the config's default.
+	ExtrapolationLookback int `json:"extrapolationLookback,omitempty"`
+	// DJRole is the id of the role which is allowed to control playback.
+	// If empty, every member may control playback.
+	DJRole string `json:"djRole,omitempty"`
+	// DJCommands holds the names of the commands that require the DJ role.
+	// A nil value uses the bot's defaults, an empty list means no command
+	// requires the role. The field is therefore stored even if empty.
+	DJCommands []string `json:"djCommands"`
+	// RequesterSkip controls whether or not the member who requested the
+	// currently playing song may skip it without the DJ role. A nil value
+	// allows it.
+	RequesterSkip *bool `json:"requesterSkip,omitempty"`
+	// VoteSkipFraction is the fraction of listeners that need to vote in order
+	// to skip a song. A zero value uses the default of one half.
+	VoteSkipFraction float64 `json:"voteSkipFraction,omitempty"`
+	// AnnounceChannel is the id of the text channel to announce songs in.
+	AnnounceChannel string `json:"announceChannel,omitempty"`
+	// MaxQueuePerUser is the maximum number of songs a single user may have in
+	// the queue at once. A zero value means no limit.
+	MaxQueuePerUser int `json:"maxQueuePerUser,omitempty"`
+}
+
+// DefaultGuildSettings returns the default settings of a guild.
+func DefaultGuildSettings() GuildSettings {
+	return GuildSettings{
+		Volume: 100,
+	}
+}
+
+// ShouldExtrapolate returns whether or not to extrapolate when the queue is
+// empty, falling back to the config's default.
+func (s GuildSettings) ShouldExtrapolate(config *Config) bool {
+	if s.Extrapolate != nil {
+		return *s.Extrapolate
+	}
+
+	return config.ExtrapolateWhenEmpty
+}
+
+// Lookback returns the number of songs to base extrapolation on, falling back
+// to the config's default.
+func (s GuildSettings) Lookback(config *Config) int {
+	if s.ExtrapolationLookback > 0 {
+		return s.ExtrapolationLookback
+	}
+
+	return config.ExtrapolationLookback
+}
+
+// AllowsRequesterSkip returns whether or not the member who requested the
+// currently playing song may skip it without the DJ role.
+func (s GuildSettings) AllowsRequesterSkip() bool {
+	if s.RequesterSkip != nil {
+		return *s.RequesterSkip
+	}
+
+	return true
+}
+
+// VoteSkipThreshold returns the fraction of listeners that need to vote in
+// order to skip a song.
+func (s GuildSettings) VoteSkipThreshold() float64 {
+	if s.VoteSkipFraction > 0 {
+		return s.VoteSkipFraction
+	}
+
+	return 0.5
+}
+
+// Guilds holds per-guild settings.
+type Guilds struct {
+	mutex    sync.Mutex
+	settings map[string]GuildSettings
+}
+
+func NewGuilds() *Guilds {
+	return &Guilds{
+		settings: make(map[string]GuildSettings),
+	}
+}
+
+// CreateGuildsIfNotExists makes sure that a guilds file exists. If it doesn't,
+// it is created.
+func CreateGuildsIfNotExists(path string) error {
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	guilds := NewGuilds()
+	return guilds.Store(path)
+}
+
+// ReadGuilds reads a guilds file from the specified path.
+func ReadGuilds(path string) (*Guilds, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var guilds Guilds
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&guilds); err != nil {
+		return nil, err
+	}
+
+	return &guilds, nil
+}
+
+// Store stores the guilds in the specified path.
+// Writes are atomic.
+func (g *Guilds) Store(path string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(g); err != nil {
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (g *Guilds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"version": "1",
+		"guilds":  g.settings,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (g *Guilds) UnmarshalJSON(data []byte) error {
+	var values struct {
+		Version string                   `json:"version"`
+		Guilds  map[string]GuildSettings `json:"guilds"`
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	if values.Guilds == nil {
+		values.Guilds = make(map[string]GuildSettings)
+	}
+
+	*g = Guilds{
+		settings: values.Guilds,
+	}
+	return nil
+}
+
+// Settings returns the settings of a guild. Returns the default settings if
+// the guild has no settings.
+func (g *Guilds) Settings(guildID string) GuildSettings {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	settings, ok := g.settings[guildID]
+	if !ok {
+		return DefaultGuildSettings()
+	}
+
+	return settings
+}
+
+// SetSettings sets the settings of a guild.
+func (g *Guilds) SetSettings(guildID string, settings GuildSettings) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.settings[guildID] = settings
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuildsStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guilds.json")
+	require.NoError(t, CreateGuildsIfNotExists(path))
+
+	guilds, err := ReadGuilds(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultGuildSettings(), guilds.Settings("1"))
+
+	settings := DefaultGuildSettings()
+	settings.Volume = 0
+	settings.DJRole = "2"
+	guilds.SetSettings("1", settings)
+	require.NoError(t, guilds.Store(path))
+
+	read, err := ReadGuilds(path)
+	require.NoError(t, err)
+	assert.Equal(t, settings, read.Settings("1"))
+	assert.Equal(t, DefaultGuildSettings(), read.Settings("3"))
+}
+
+func TestGuildsStoreEmptyDJCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guilds.json")
+
+	guilds := NewGuilds()
+	guilds.SetSettings("1", GuildSettings{DJCommands: []string{}})
+	guilds.SetSettings("2", GuildSettings{})
+	require.NoError(t, guilds.Store(path))
+
+	read, err := ReadGuilds(path)
+	require.NoError(t, err)
+
+	// No commands requiring the role is different from using the defaults
+	assert.NotNil(t, read.Settings("1").DJCommands)
+	assert.Empty(t, read.Settings("1").DJCommands)
+	assert.Nil(t, read.Settings("2").DJCommands)
+}
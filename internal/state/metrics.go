@@ -8,6 +8,12 @@ type Metrics struct {
 	SongsPlayed    prometheus.Counter
 	DurationPlayed prometheus.Counter
 	ActiveStreams  prometheus.Gauge
+	// VoiceReconnects counts attempts to reconnect dropped voice connections
+	// by result, either "success" or "failure".
+	VoiceReconnects *prometheus.CounterVec
+	// SongsFailed counts songs which could not be played by reason, such as
+	// "private" or "rate_limited".
+	SongsFailed *prometheus.CounterVec
 }
 
 func NewMetrics() *Metrics {
@@ -30,6 +36,18 @@ func NewMetrics() *Metrics {
 			Name:      "active_streams",
 			Help:      "Number of currently active streams",
 		}),
+		VoiceReconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clabbe",
+			Subsystem: "discord",
+			Name:      "voice_reconnects_total",
+			Help:      "Total number of attempts to reconnect dropped voice connections",
+		}, []string{"result"}),
+		SongsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clabbe",
+			Subsystem: "core",
+			Name:      "songs_failed_total",
+			Help:      "Total number of songs which could not be played",
+		}, []string{"reason"}),
 	}
 }
 
@@ -37,6 +55,8 @@ func NewMetrics() *Metrics {
 func (m *Metrics) Collect(c chan<- prometheus.Metric) {
 	m.SongsPlayed.Collect(c)
 	m.DurationPlayed.Collect(c)
+	m.VoiceReconnects.Collect(c)
+	m.SongsFailed.Collect(c)
 }
 
 // Describe implements prometheus.Collector.
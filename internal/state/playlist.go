@@ -3,6 +3,7 @@ package state
 import (
 	"encoding/json"
 	"errors"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +25,16 @@ type Source string
 
 const (
 	SourceYouTube Source = "youtube"
+	// SourceLocal refers to files of a local music library. URIs are paths
+	// relative to the library.
+	SourceLocal Source = "local"
+	// SourceRadio refers to live HTTP streams, such as Icecast radio stations.
+	// URIs are the URLs of the streams.
+	SourceRadio Source = "radio"
+	// SourceGeneric refers to media of any site supported by yt-dlp, such as
+	// SoundCloud. Entries use the name of the site's extractor as their source,
+	// such as "soundcloud". URIs are the URLs of the media's web pages.
+	SourceGeneric Source = "generic"
 )
 
 type Entity struct {
@@ -46,6 +57,8 @@ type PlaylistEntry struct {
 	Source Source `json:"source"`
 	// URI is a source-specific URI that uniquely refers to the entry.
 	URI string `json:"uri"`
+	// Duration is the duration of the entry, if known.
+	Duration time.Duration `json:"duration,omitempty"`
 }
 
 type Playlist struct {
@@ -200,16 +213,33 @@ func (p *Playlist) PeakBackN(n int) []PlaylistEntry {
 	return entries
 }
 
+// Entries returns a copy of all entries in the playlist.
+func (p *Playlist) Entries() []PlaylistEntry {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	entries := make([]PlaylistEntry, len(p.entries))
+	copy(entries, p.entries)
+	return entries
+}
+
 // Format formats the first n entries of the playlist using the specified format
-// template.
+// template. See FormatPage.
+func (p *Playlist) Format(format string, n int, reversed bool) (string, error) {
+	return p.FormatPage(format, 0, n, reversed)
+}
+
+// FormatPage formats at most n entries of the playlist, starting at offset,
+// using the specified format template. If reversed is true, the offset is
+// counted from the back of the playlist.
 //
 // The template has the following values exposed:
 //
-//   - Index: index of the entry
+//   - Index: index of the entry, starting at offset + 1
 //   - EntityName: name of the entity that added the entry
 //   - Title: title of the entry
 //   - RelativeTime: duration since the entry was added
-func (p *Playlist) Format(format string, n int, reversed bool) (string, error) {
+func (p *Playlist) FormatPage(format string, offset int, n int, reversed bool) (string, error) {
 	t, err := template.New("").Parse(format)
 	if err != nil {
 		return "", err
@@ -220,7 +250,7 @@ func (p *Playlist) Format(format string, n int, reversed bool) (string, error) {
 
 	var builder strings.Builder
 
-	for i := 0; i < n && i < len(p.entries); i++ {
+	for i := max(offset, 0); i < offset+n && i < len(p.entries); i++ {
 		entry := p.entries[i]
 		if reversed {
 			entry = p.entries[len(p.entries)-1-i]
@@ -247,6 +277,24 @@ func (p *Playlist) Format(format string, n int, reversed bool) (string, error) {
 	return builder.String(), nil
 }
 
+// Shuffle shuffles the entries of the playlist.
+func (p *Playlist) Shuffle() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	rand.Shuffle(len(p.entries), func(i, j int) {
+		p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	})
+}
+
+// Len returns the number of entries in the playlist.
+func (p *Playlist) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.entries)
+}
+
 // Clear clears the playlist.
 func (p *Playlist) Clear() {
 	p.entries = make([]PlaylistEntry, 0)
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylistFormatPage(t *testing.T) {
+	playlist := NewPlaylist()
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		playlist.Push(PlaylistEntry{Title: title})
+	}
+
+	testCases := []struct {
+		Name     string
+		Offset   int
+		N        int
+		Reversed bool
+		Expected string
+	}{
+		{
+			Name:     "first page",
+			Offset:   0,
+			N:        2,
+			Expected: "1.a 2.b ",
+		},
+		{
+			Name:     "last page",
+			Offset:   4,
+			N:        2,
+			Expected: "5.e ",
+		},
+		{
+			Name:     "reversed",
+			Offset:   2,
+			N:        2,
+			Reversed: true,
+			Expected: "3.c 4.b ",
+		},
+		{
+			Name:     "out of range",
+			Offset:   10,
+			N:        2,
+			Expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			actual, err := playlist.FormatPage("{{.Index}}.{{.Title}} ", testCase.Offset, testCase.N, testCase.Reversed)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.Expected, actual)
+		})
+	}
+}
@@ -1,28 +1,52 @@
 package state
 
-const DefaultPrompt = `You are a radio DJ. The user will provide one or more artist, song, album, genre or vibe or ask you to continue with similar songs. Respond with a newline-separated list of five song names and their original artist. The songs must match the user’s request. Don't include already presented songs. Don’t respond with anything other than the name of songs followed by the artist’s name. Don’t repeat names. You have a bias towards older songs. If you can’t come up with any songs that match the request, respond with “no results”. Do not make anything up! When asked to play songs for a certain vibe, try hard to come up with songs that generally match the description.`
-
-const GenerateThemesPrompt = `Generate themes for playlists.  Respond with one theme per line. Respond with at most five examples. Don't respond with seasonal themes such as Christmas songs. Don't name specific artists or songs. Be specific, don't just specify a genre. Don't be political.
-
-Good examples:
-
-60s psychadelic rock
-Electro-swing dance tracks
-Lo-fi beats
-80s new wave hits
-Uncommon blues songs
-Songs for late-night drives
-Motown classics
-
-Bad examples:
-
-Road trip
-Acoustic folk
-Songs for rainy days
-Latin party
-Workout songs
-Upbeat anthems
-Study music
-Feel-good
-Cultural heritage
-Empowerment`
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Prompt templates are read from the following files in the config directory,
+// if they exist. If they don't, the bot's default templates are used.
+const (
+	SongSuggestionPromptFile  = "prompt-song-suggestion.tmpl"
+	ThemeSuggestionPromptFile = "prompt-theme-suggestion.tmpl"
+)
+
+// PromptPaths returns the paths to the prompt template files that may exist
+// next to the config file at path.
+func PromptPaths(path string) []string {
+	return []string{
+		filepath.Join(filepath.Dir(path), SongSuggestionPromptFile),
+		filepath.Join(filepath.Dir(path), ThemeSuggestionPromptFile),
+	}
+}
+
+// readPrompts populates the config's prompts with templates read from the
+// specified directory.
+func (c *Config) readPrompts(dir string) error {
+	prompt, err := readPromptIfExists(filepath.Join(dir, SongSuggestionPromptFile))
+	if err != nil {
+		return err
+	}
+	c.Prompt = prompt
+
+	themesPrompt, err := readPromptIfExists(filepath.Join(dir, ThemeSuggestionPromptFile))
+	if err != nil {
+		return err
+	}
+	c.ThemesPrompt = themesPrompt
+
+	return nil
+}
+
+func readPromptIfExists(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
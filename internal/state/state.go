@@ -3,11 +3,14 @@ package state
 import (
 	"os"
 	"path"
+	"sync/atomic"
 )
 
 // State holds application state.
 type State struct {
-	Config *Config
+	configPath      string
+	configOverrides []ConfigOverride
+	config          atomic.Pointer[Config]
 
 	queuePath string
 	Queue     *Playlist
@@ -18,17 +21,28 @@ type State struct {
 	historyPath string
 	History     *Playlist
 
+	likesPath string
+	Likes     *Playlist
+
+	guildsPath string
+	Guilds     *Guilds
+
 	Metrics *Metrics
 }
 
 // LoadOrInit loads the state from the specified base path.
 // If the state is not initialized (i.e. config files etc. not created), the
 // state is initialized.
-func LoadOrInit(basePath string) (*State, error) {
+//
+// The config is populated in the following order, where later sources take
+// precedence: defaults, config file, environment variables and overrides.
+func LoadOrInit(basePath string, overrides ...ConfigOverride) (*State, error) {
 	configPath := path.Join(basePath, "config.yaml")
 	queuePath := path.Join(basePath, "queue.json")
 	suggestionsPath := path.Join(basePath, "suggestions.json")
 	historyPath := path.Join(basePath, "history.json")
+	likesPath := path.Join(basePath, "likes.json")
+	guildsPath := path.Join(basePath, "guilds.json")
 
 	if err := os.MkdirAll(basePath, os.ModePerm); err != nil {
 		return nil, err
@@ -44,6 +58,9 @@ func LoadOrInit(basePath string) (*State, error) {
 	if err := config.PopulateFromEnvironment(); err != nil {
 		return nil, err
 	}
+	for _, override := range overrides {
+		override(config)
+	}
 
 	if err := CreatePlaylistIfNotExists(queuePath); err != nil {
 		return nil, err
@@ -69,8 +86,25 @@ func LoadOrInit(basePath string) (*State, error) {
 		return nil, err
 	}
 
-	return &State{
-		Config: config,
+	if err := CreatePlaylistIfNotExists(likesPath); err != nil {
+		return nil, err
+	}
+	likes, err := ReadPlaylist(likesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CreateGuildsIfNotExists(guildsPath); err != nil {
+		return nil, err
+	}
+	guilds, err := ReadGuilds(guildsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{
+		configPath:      configPath,
+		configOverrides: overrides,
 
 		queuePath: queuePath,
 		Queue:     queue,
@@ -81,8 +115,60 @@ func LoadOrInit(basePath string) (*State, error) {
 		historyPath: historyPath,
 		History:     history,
 
+		likesPath: likesPath,
+		Likes:     likes,
+
+		guildsPath: guildsPath,
+		Guilds:     guilds,
+
 		Metrics: NewMetrics(),
-	}, nil
+	}
+	state.SetConfig(config)
+
+	return state, nil
+}
+
+// Config returns the current config. The config is replaced as a whole when
+// reloaded, so it must not be modified. Callers reading several values that
+// must be consistent should hold on to the returned config rather than calling
+// Config repeatedly.
+func (s *State) Config() *Config {
+	return s.config.Load()
+}
+
+// SetConfig replaces the current config.
+func (s *State) SetConfig(config *Config) {
+	s.config.Store(config)
+}
+
+// ReloadConfig reads the config from the same location it was initially read
+// from and applies the changes that can be made at runtime by replacing the
+// current config.
+// Returns an error and leaves the config untouched if the new config is
+// invalid.
+func (s *State) ReloadConfig() (ConfigChanges, error) {
+	config, err := ReadConfig(s.configPath)
+	if err != nil {
+		return ConfigChanges{}, err
+	}
+	if err := config.PopulateFromEnvironment(); err != nil {
+		return ConfigChanges{}, err
+	}
+	for _, override := range s.configOverrides {
+		override(config)
+	}
+	if err := config.Validate(); err != nil {
+		return ConfigChanges{}, err
+	}
+
+	applied, changes := s.Config().Apply(config)
+	s.SetConfig(applied)
+	return changes, nil
+}
+
+// ConfigPath returns the path to the config file.
+func (s *State) ConfigPath() string {
+	return s.configPath
 }
 
 // Store stores the state to the same location it was read from.
@@ -99,5 +185,13 @@ func (s *State) Store() error {
 		return err
 	}
 
+	if err := s.Likes.Store(s.likesPath); err != nil {
+		return err
+	}
+
+	if err := s.Guilds.Store(s.guildsPath); err != nil {
+		return err
+	}
+
 	return nil
 }
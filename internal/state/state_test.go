@@ -0,0 +1,45 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+
+	state, err := LoadOrInit(dir)
+	require.NoError(t, err)
+	current := state.Config()
+
+	require.NoError(t, os.WriteFile(configPath, []byte("discordBotToken: token\nplaylistLimit: 10\n"), 0644))
+
+	// Readers may hold on to the config while it's reloaded
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 100 {
+			_ = state.Config().PlaylistLimit
+		}
+	})
+	changes, err := state.ReloadConfig()
+	wg.Wait()
+	require.NoError(t, err)
+
+	assert.Contains(t, changes.Applied, "playlistLimit")
+	assert.Contains(t, changes.RequiresRestart, "discordBotToken")
+	assert.Equal(t, 10, state.Config().PlaylistLimit)
+	assert.Equal(t, "", state.Config().DiscordBotToken)
+	assert.Equal(t, 50, current.PlaylistLimit)
+
+	// Invalid templates are rejected, keeping the current config
+	require.NoError(t, os.WriteFile(filepath.Join(dir, SongSuggestionPromptFile), []byte("{{ range .history }}"), 0644))
+	_, err = state.ReloadConfig()
+	assert.Error(t, err)
+	assert.Equal(t, "", state.Config().Prompt)
+}
@@ -0,0 +1,278 @@
+// Package tags reads metadata of audio files.
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexGustafsson/clabbe/internal/ogg"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported format")
+)
+
+type Codec string
+
+const (
+	CodecFLAC   Codec = "flac"
+	CodecOpus   Codec = "opus"
+	CodecVorbis Codec = "vorbis"
+)
+
+// Tags holds the metadata of an audio file.
+type Tags struct {
+	Codec       Codec
+	Title       string
+	Artist      string
+	Album       string
+	TrackNumber int
+	Duration    time.Duration
+}
+
+// Read reads the tags of a FLAC or Ogg (Opus or Vorbis) file. Returns
+// ErrUnsupportedFormat for other files.
+func Read(path string) (Tags, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer file.Close()
+
+	return ReadFrom(file)
+}
+
+// ReadFrom reads the tags of a FLAC or Ogg (Opus or Vorbis) stream. Returns
+// ErrUnsupportedFormat for other streams.
+func ReadFrom(r io.ReadSeeker) (Tags, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return Tags{}, ErrUnsupportedFormat
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return Tags{}, err
+	}
+
+	switch string(magic) {
+	case "fLaC":
+		return readFLAC(r)
+	case "OggS":
+		return readOgg(r)
+	default:
+		return Tags{}, ErrUnsupportedFormat
+	}
+}
+
+// readFLAC reads the tags of a FLAC stream.
+// SEE: https://www.rfc-editor.org/rfc/rfc9639
+func readFLAC(r io.Reader) (Tags, error) {
+	tags := Tags{
+		Codec: CodecFLAC,
+	}
+
+	// Skip the marker
+	if _, err := io.ReadFull(r, make([]byte, 4)); err != nil {
+		return Tags{}, err
+	}
+
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return Tags{}, err
+		}
+
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return Tags{}, err
+		}
+
+		switch blockType {
+		// STREAMINFO
+		case 0:
+			if size < 18 {
+				return Tags{}, fmt.Errorf("tags: invalid flac stream info")
+			}
+
+			// Sample rate (20 bits), channels (3 bits), bits per sample (5 bits) and
+			// total samples (36 bits)
+			info := binary.BigEndian.Uint64(block[10:18])
+			sampleRate := info >> 44
+			samples := info & (1<<36 - 1)
+			if sampleRate > 0 {
+				tags.Duration = time.Duration(samples) * time.Second / time.Duration(sampleRate)
+			}
+		// VORBIS_COMMENT
+		case 4:
+			comments, err := parseVorbisComment(block)
+			if err != nil {
+				return Tags{}, err
+			}
+			tags.apply(comments)
+		}
+
+		if last {
+			return tags, nil
+		}
+	}
+}
+
+// readOgg reads the tags of an Ogg Opus or Ogg Vorbis stream.
+// SEE: https://www.rfc-editor.org/rfc/rfc7845
+// SEE: https://xiph.org/vorbis/doc/Vorbis_I_spec.html
+func readOgg(r io.ReadSeeker) (Tags, error) {
+	reader := ogg.NewReader(r)
+
+	identification, err := reader.ReadPacket()
+	if err != nil {
+		return Tags{}, err
+	}
+
+	comment, err := reader.ReadPacket()
+	if err != nil {
+		return Tags{}, err
+	}
+
+	var tags Tags
+	var sampleRate uint64
+	var preSkip uint64
+	switch {
+	case bytes.HasPrefix(identification, []byte("OpusHead")) && len(identification) >= 19:
+		tags.Codec = CodecOpus
+		// Opus is always decoded at 48kHz
+		sampleRate = 48000
+		preSkip = uint64(binary.LittleEndian.Uint16(identification[10:12]))
+
+		if !bytes.HasPrefix(comment, []byte("OpusTags")) {
+			return Tags{}, fmt.Errorf("tags: missing opus tags")
+		}
+		comment = comment[8:]
+	case bytes.HasPrefix(identification, []byte("\x01vorbis")) && len(identification) >= 16:
+		tags.Codec = CodecVorbis
+		sampleRate = uint64(binary.LittleEndian.Uint32(identification[12:16]))
+
+		if !bytes.HasPrefix(comment, []byte("\x03vorbis")) {
+			return Tags{}, fmt.Errorf("tags: missing vorbis comment")
+		}
+		comment = comment[7:]
+	default:
+		return Tags{}, ErrUnsupportedFormat
+	}
+
+	comments, err := parseVorbisComment(comment)
+	if err != nil {
+		return Tags{}, err
+	}
+	tags.apply(comments)
+
+	granulePosition, err := ogg.LastGranulePosition(r)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	if sampleRate > 0 && granulePosition > preSkip {
+		tags.Duration = time.Duration(granulePosition-preSkip) * time.Second / time.Duration(sampleRate)
+	}
+
+	return tags, nil
+}
+
+// ParseOpusTags parses the tags of an OpusTags packet, such as the tags sent
+// by a radio station when it starts playing another song. The codec and
+// duration are left unset.
+// SEE: https://www.rfc-editor.org/rfc/rfc7845#section-5.2
+func ParseOpusTags(packet []byte) (Tags, error) {
+	comment, ok := bytes.CutPrefix(packet, []byte("OpusTags"))
+	if !ok {
+		return Tags{}, fmt.Errorf("tags: missing opus tags")
+	}
+
+	comments, err := parseVorbisComment(comment)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	var tags Tags
+	tags.apply(comments)
+	return tags, nil
+}
+
+// parseVorbisComment parses the comments of a Vorbis comment. Keys are upper
+// cased.
+// SEE: https://xiph.org/vorbis/doc/v-comment.html
+func parseVorbisComment(data []byte) (map[string]string, error) {
+	reader := bytes.NewReader(data)
+
+	readString := func() (string, error) {
+		var size uint32
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return "", err
+		}
+
+		if int64(size) > int64(reader.Len()) {
+			return "", fmt.Errorf("tags: invalid vorbis comment length")
+		}
+
+		value := make([]byte, size)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+
+	// Vendor
+	if _, err := readString(); err != nil {
+		return nil, err
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]string)
+	for i := uint32(0); i < count; i++ {
+		comment, err := readString()
+		if err != nil {
+			return nil, err
+		}
+
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+
+		// Keep the first value of repeated keys
+		key = strings.ToUpper(key)
+		if _, ok := comments[key]; !ok {
+			comments[key] = value
+		}
+	}
+
+	return comments, nil
+}
+
+// apply sets the tags from Vorbis comments.
+func (t *Tags) apply(comments map[string]string) {
+	t.Title = comments["TITLE"]
+	t.Artist = comments["ARTIST"]
+	if t.Artist == "" {
+		t.Artist = comments["ALBUMARTIST"]
+	}
+	t.Album = comments["ALBUM"]
+
+	// Track numbers may be written as "3/12"
+	number, _, _ := strings.Cut(comments["TRACKNUMBER"], "/")
+	t.TrackNumber, _ = strconv.Atoi(strings.TrimSpace(number))
+}
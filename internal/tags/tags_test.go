@@ -0,0 +1,104 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func vorbisComment(comments ...string) []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, uint32(len("test")))
+	buffer.WriteString("test")
+	binary.Write(&buffer, binary.LittleEndian, uint32(len(comments)))
+	for _, comment := range comments {
+		binary.Write(&buffer, binary.LittleEndian, uint32(len(comment)))
+		buffer.WriteString(comment)
+	}
+	return buffer.Bytes()
+}
+
+// oggPage encodes a page holding a single packet shorter than 255 bytes.
+func oggPage(granulePosition uint64, packet []byte) []byte {
+	header := make([]byte, 27)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], granulePosition)
+	header[26] = 1
+
+	var buffer bytes.Buffer
+	buffer.Write(header)
+	buffer.WriteByte(byte(len(packet)))
+	buffer.Write(packet)
+	return buffer.Bytes()
+}
+
+func TestReadFLAC(t *testing.T) {
+	var file bytes.Buffer
+	file.WriteString("fLaC")
+
+	// STREAMINFO of 10s at 44.1kHz
+	streamInfo := make([]byte, 34)
+	binary.BigEndian.PutUint64(streamInfo[10:18], uint64(44100)<<44|uint64(441000))
+	file.Write([]byte{0x00, 0x00, 0x00, byte(len(streamInfo))})
+	file.Write(streamInfo)
+
+	comment := vorbisComment("TITLE=Song", "artist=Artist", "ALBUM=Album", "TRACKNUMBER=3/12")
+	file.Write([]byte{0x80 | 0x04, 0x00, 0x00, byte(len(comment))})
+	file.Write(comment)
+
+	tags, err := ReadFrom(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+
+	expected := Tags{
+		Codec:       CodecFLAC,
+		Title:       "Song",
+		Artist:      "Artist",
+		Album:       "Album",
+		TrackNumber: 3,
+		Duration:    10 * time.Second,
+	}
+	assert.Equal(t, expected, tags)
+}
+
+func TestReadOpus(t *testing.T) {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = 2
+	binary.LittleEndian.PutUint16(head[10:12], 312)
+
+	var file bytes.Buffer
+	file.Write(oggPage(0, head))
+	file.Write(oggPage(0, append([]byte("OpusTags"), vorbisComment("TITLE=Song", "ALBUMARTIST=Artist")...)))
+	// 2s of audio
+	file.Write(oggPage(312+96000, []byte{0xFC}))
+
+	tags, err := ReadFrom(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+
+	expected := Tags{
+		Codec:    CodecOpus,
+		Title:    "Song",
+		Artist:   "Artist",
+		Duration: 2 * time.Second,
+	}
+	assert.Equal(t, expected, tags)
+}
+
+func TestReadUnsupported(t *testing.T) {
+	_, err := ReadFrom(bytes.NewReader([]byte("ID3\x03")))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestParseOpusTags(t *testing.T) {
+	tags, err := ParseOpusTags(append([]byte("OpusTags"), vorbisComment("TITLE=Song", "ARTIST=Artist")...))
+	require.NoError(t, err)
+	assert.Equal(t, Tags{Title: "Song", Artist: "Artist"}, tags)
+
+	_, err = ParseOpusTags([]byte("OpusHead"))
+	assert.Error(t, err)
+}
@@ -32,3 +32,20 @@ func FormatRelativeDuration(duration time.Duration) string {
 		return fmt.Sprintf("%s%gs%s", prefix, math.Round(duration.Seconds()), postfix)
 	}
 }
+
+// FormatDuration formats a duration as a timestamp.
+//
+//	FormatDuration(65 * time.Second) // 1:05
+//	FormatDuration(time.Hour + 5*time.Second) // 1:00:05
+func FormatDuration(duration time.Duration) string {
+	duration = duration.Round(time.Second)
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	seconds := int(duration.Seconds()) % 60
+
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
@@ -44,3 +44,29 @@ func TestFormatRelativeDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatDuration(t *testing.T) {
+	testCases := []struct {
+		Duration time.Duration
+		Expected string
+	}{
+		{
+			Duration: 5 * time.Second,
+			Expected: "0:05",
+		},
+		{
+			Duration: 65 * time.Second,
+			Expected: "1:05",
+		},
+		{
+			Duration: time.Hour + 5*time.Second,
+			Expected: "1:00:05",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Duration.String(), func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, FormatDuration(testCase.Duration))
+		})
+	}
+}
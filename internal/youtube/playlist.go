@@ -0,0 +1,126 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Playlist returns at most limit videos of a playlist.
+func Playlist(ctx context.Context, id string, limit int) ([]SearchResult, error) {
+	client := NewSearchClient()
+	return client.Playlist(ctx, id, limit)
+}
+
+// Playlist returns at most limit videos of a playlist.
+// NOTE: Only the first page of the playlist, about 100 videos, is available.
+func (c *SearchClient) Playlist(ctx context.Context, id string, limit int) ([]SearchResult, error) {
+	playlistURL := url.URL{
+		Scheme:   "https",
+		Host:     "www.youtube.com",
+		Path:     "/playlist",
+		RawQuery: url.Values{"list": []string{id}}.Encode(),
+	}
+
+	slog.Debug("Fetching playlist", slog.String("id", id))
+	initialData, err := c.initialData(ctx, playlistURL)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := parsePlaylist(initialData)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	slog.Debug("Successfully fetched playlist", slog.Int("results", len(results)))
+	return results, nil
+}
+
+// parsePlaylist parses the videos of a playlist page's initial data.
+func parsePlaylist(data []byte) ([]SearchResult, error) {
+	var initialData struct {
+		Contents struct {
+			TwoColumnBrowseResultsRenderer struct {
+				Tabs []struct {
+					TabRenderer struct {
+						Content struct {
+							SectionListRenderer struct {
+								Contents []struct {
+									ItemSectionRenderer struct {
+										Contents []struct {
+											PlaylistVideoListRenderer struct {
+												Contents []struct {
+													PlaylistVideoRenderer struct {
+														VideoID string `json:"videoId"`
+														Title   struct {
+															Runs []struct {
+																Text string `json:"text"`
+															} `json:"runs"`
+														} `json:"title"`
+														ShortBylineText struct {
+															Runs []struct {
+																Text string `json:"text"`
+															} `json:"runs"`
+														} `json:"shortBylineText"`
+														LengthSeconds string `json:"lengthSeconds"`
+														IsPlayable    bool   `json:"isPlayable"`
+													} `json:"playlistVideoRenderer"`
+												} `json:"contents"`
+											} `json:"playlistVideoListRenderer"`
+										} `json:"contents"`
+									} `json:"itemSectionRenderer"`
+								} `json:"contents"`
+							} `json:"sectionListRenderer"`
+						} `json:"content"`
+					} `json:"tabRenderer"`
+				} `json:"tabs"`
+			} `json:"twoColumnBrowseResultsRenderer"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(data, &initialData); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0)
+	for _, tab := range initialData.Contents.TwoColumnBrowseResultsRenderer.Tabs {
+		for _, content := range tab.TabRenderer.Content.SectionListRenderer.Contents {
+			for _, content := range content.ItemSectionRenderer.Contents {
+				for _, content := range content.PlaylistVideoListRenderer.Contents {
+					video := content.PlaylistVideoRenderer
+					// Deleted and private videos are not playable
+					if video.VideoID == "" || !video.IsPlayable {
+						continue
+					}
+
+					title := ""
+					if len(video.Title.Runs) > 0 {
+						title = video.Title.Runs[0].Text
+					}
+
+					channel := ""
+					if len(video.ShortBylineText.Runs) > 0 {
+						channel = video.ShortBylineText.Runs[0].Text
+					}
+
+					seconds, _ := strconv.Atoi(video.LengthSeconds)
+					results = append(results, SearchResult{
+						ID:       video.VideoID,
+						Title:    title,
+						Channel:  channel,
+						Duration: time.Duration(seconds) * time.Second,
+					})
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
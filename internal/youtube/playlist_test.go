@@ -0,0 +1,30 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	data := `{"contents":{"twoColumnBrowseResultsRenderer":{"tabs":[{"tabRenderer":{"content":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"playlistVideoListRenderer":{"contents":[
+		{"playlistVideoRenderer":{"videoId":"dQw4w9WgXcQ","title":{"runs":[{"text":"Never Gonna Give You Up"}]},"shortBylineText":{"runs":[{"text":"Rick Astley"}]},"lengthSeconds":"213","isPlayable":true}},
+		{"playlistVideoRenderer":{"videoId":"BaW_jenozKc","title":{"runs":[{"text":"[Private video]"}]},"isPlayable":false}},
+		{"continuationItemRenderer":{}}
+	]}}]}}]}}}}]}}}`
+
+	results, err := parsePlaylist([]byte(data))
+	require.NoError(t, err)
+
+	expected := []SearchResult{
+		{
+			ID:       "dQw4w9WgXcQ",
+			Title:    "Never Gonna Give You Up",
+			Channel:  "Rick Astley",
+			Duration: 213 * time.Second,
+		},
+	}
+	assert.Equal(t, expected, results)
+}
@@ -16,8 +16,8 @@ import (
 	"time"
 )
 
-var leadingNumbersRegex = regexp.MustCompile(`^[\d\s\x{00A0}]+`)
-var whitespaceRegex = regexp.MustCompile(`[\s\x{00A0}]`)
+var leadingNumbersRegex = regexp.MustCompile(`^\d[\d\s\x{00A0},.]*`)
+var separatorRegex = regexp.MustCompile(`[\s\x{00A0},.]`)
 
 type RecommendClient struct {
 	client *http.Client
@@ -169,8 +169,9 @@ func parseViewCount(text string) (int, error) {
 		return 0, fmt.Errorf("invalid view count - expected leading numbers")
 	}
 
-	// Count may contain non-breaking spaces and other types of whitespace
-	count, err := strconv.ParseInt(whitespaceRegex.ReplaceAllString(countValue, ""), 10, 32)
+	// Count may contain thousands separators such as commas, dots and
+	// non-breaking spaces
+	count, err := strconv.ParseInt(separatorRegex.ReplaceAllString(countValue, ""), 10, 0)
 	if err != nil {
 		return 0, err
 	}
@@ -24,3 +24,13 @@ func TestParseViewCount(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 7867, count)
 }
+
+func TestParseViewCountSeparators(t *testing.T) {
+	count, err := parseViewCount("8,439,217,051 views")
+	assert.NoError(t, err)
+	assert.Equal(t, 8439217051, count)
+
+	count, err = parseViewCount("1.234 Aufrufe")
+	assert.NoError(t, err)
+	assert.Equal(t, 1234, count)
+}
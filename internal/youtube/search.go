@@ -12,8 +12,8 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
-	"slices"
 	"strings"
+	"time"
 )
 
 var initialDataRegex = regexp.MustCompile(`var ytInitialData = (.*?)};`)
@@ -48,12 +48,106 @@ func Search(ctx context.Context, query string) ([]SearchResult, error) {
 	return client.Search(ctx, query)
 }
 
+// Lookup looks up a video by its id.
+func Lookup(ctx context.Context, id string) (SearchResult, bool, error) {
+	client := NewSearchClient()
+	return client.Lookup(ctx, id)
+}
+
 type SearchResult struct {
-	ID    string
-	Title string
+	ID       string
+	Title    string
+	Channel  string
+	Duration time.Duration
+	// Views is the view count of the video. Zero if unknown.
+	Views int
+	// Live is true if the video is a livestream.
+	Live bool
+	// Short is true if the video is a YouTube Short.
+	Short bool
+	// ChannelBadge describes the verification of the channel, if any.
+	ChannelBadge ChannelBadge
+	// Thumbnails holds the video's thumbnails, smallest first.
+	Thumbnails []Thumbnail
+}
+
+// ChannelBadge describes the verification of a channel.
+type ChannelBadge int
+
+const (
+	ChannelBadgeNone ChannelBadge = iota
+	// ChannelBadgeVerified is shown for verified channels.
+	ChannelBadgeVerified
+	// ChannelBadgeArtist is shown for official artist channels.
+	ChannelBadgeArtist
+)
+
+type Thumbnail struct {
+	URL    string
+	Width  int
+	Height int
 }
 
 func (c *SearchClient) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	page, err := c.SearchPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Results, nil
+}
+
+// maxSearchPages is the maximum number of pages fetched by SearchN.
+const maxSearchPages = 3
+
+// SearchN searches for at least n results kept by keep, fetching additional
+// pages as required. All results are kept if keep is nil. Fewer results are
+// returned if there are no more pages or if maxSearchPages pages have been
+// fetched.
+func (c *SearchClient) SearchN(ctx context.Context, query string, n int, keep func(SearchResult) bool) ([]SearchResult, error) {
+	page, err := c.SearchPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0)
+	for i := 1; ; i++ {
+		for _, result := range page.Results {
+			if keep == nil || keep(result) {
+				results = append(results, result)
+			}
+		}
+
+		if len(results) >= n || i == maxSearchPages || !page.HasNext() {
+			return results, nil
+		}
+
+		page, err = c.NextPage(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page.Results) == 0 {
+			return results, nil
+		}
+	}
+}
+
+// SearchPage is a page of search results.
+type SearchPage struct {
+	Results []SearchResult
+	// Continuation is the token used to fetch the next page. Empty if there
+	// are no more pages.
+	Continuation string
+}
+
+// HasNext returns whether or not there are more pages.
+func (p *SearchPage) HasNext() bool {
+	return p.Continuation != ""
+}
+
+// SearchPage returns the first page of search results.
+func (c *SearchClient) SearchPage(ctx context.Context, query string) (*SearchPage, error) {
 	// Build request URL
 	searchURL := url.URL{
 		Scheme: "https",
@@ -65,78 +159,312 @@ func (c *SearchClient) Search(ctx context.Context, query string) ([]SearchResult
 	searchQuery.Set("search_query", query)
 	searchURL.RawQuery = searchQuery.Encode()
 
-	req, _ := http.NewRequest(http.MethodGet, searchURL.String(), nil)
-
 	slog.Debug("Performing search request", slog.String("query", query))
-	res, err := c.client.Do(req)
+	initialDataBytes, err := c.initialData(ctx, searchURL)
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode == http.StatusTooManyRequests {
-		return nil, ErrTooManyRequests
-	} else if res.StatusCode != http.StatusOK {
-		slog.Error("Failed to perform search", slog.String("status", res.Status))
-		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	page, err := parseSearchPage(initialDataBytes)
+	if err != nil {
+		return nil, err
 	}
 
-	// Read just as little as is requried to match the initial data
-	var buffer bytes.Buffer
-	reader := bufio.NewReader(io.TeeReader(res.Body, &buffer))
-	match := initialDataRegex.FindReaderIndex(reader)
-	if match == nil {
-		return nil, fmt.Errorf("unable to find initial data in response")
+	slog.Debug("Successfully performed search", slog.Int("results", len(page.Results)))
+	return page, nil
+}
+
+// NextPage returns the page of search results following page.
+func (c *SearchClient) NextPage(ctx context.Context, page *SearchPage) (*SearchPage, error) {
+	if !page.HasNext() {
+		return &SearchPage{Results: []SearchResult{}}, nil
 	}
 
-	// Extract the match
-	initialDataBytes := buffer.Bytes()[match[0]+20 : match[1]-1]
+	slog.Debug("Fetching next page of search results")
+	data, err := c.innertube(ctx, "search", map[string]any{
+		"continuation": page.Continuation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := parseSearchContinuation(data)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Successfully fetched next page of search results", slog.Int("results", len(next.Results)))
+	return next, nil
+}
+
+// searchSection is a section of search results. The last section holds the
+// continuation of the search, if there are more pages.
+type searchSection struct {
+	ItemSectionRenderer struct {
+		Contents []struct {
+			VideoRenderer videoRenderer `json:"videoRenderer"`
+		} `json:"contents"`
+	} `json:"itemSectionRenderer"`
+	ContinuationItemRenderer struct {
+		ContinuationEndpoint struct {
+			ContinuationCommand struct {
+				Token string `json:"token"`
+			} `json:"continuationCommand"`
+		} `json:"continuationEndpoint"`
+	} `json:"continuationItemRenderer"`
+}
+
+// parseSearchPage parses the videos and continuation of a search page's
+// initial data.
+func parseSearchPage(data []byte) (*SearchPage, error) {
 	var initialData struct {
 		Contents struct {
 			TwoColumnSearchResultsRenderer struct {
 				PrimaryContents struct {
 					SectionListRenderer struct {
-						Contents []struct {
-							ItemSectionRenderer struct {
-								Contents []struct {
-									VideoRenderer struct {
-										VideoID string `json:"videoId"`
-										Title   struct {
-											Runs []struct {
-												Text string `json:"text"`
-											} `json:"runs"`
-										} `json:"title"`
-									} `json:"videoRenderer"`
-								} `json:"contents"`
-							} `json:"itemSectionRenderer"`
-						} `json:"contents"`
+						Contents []searchSection `json:"contents"`
 					} `json:"sectionListRenderer"`
 				} `json:"primaryContents"`
 			} `json:"twoColumnSearchResultsRenderer"`
 		} `json:"contents"`
 	}
-	if err := json.Unmarshal(initialDataBytes, &initialData); err != nil {
+	if err := json.Unmarshal(data, &initialData); err != nil {
 		return nil, err
 	}
 
-	results := make([]SearchResult, 0)
-	for _, content := range initialData.Contents.TwoColumnSearchResultsRenderer.PrimaryContents.SectionListRenderer.Contents {
-		for _, content := range content.ItemSectionRenderer.Contents {
-			title := ""
-			if len(content.VideoRenderer.Title.Runs) > 0 {
-				title = content.VideoRenderer.Title.Runs[0].Text
+	return newSearchPage(initialData.Contents.TwoColumnSearchResultsRenderer.PrimaryContents.SectionListRenderer.Contents), nil
+}
+
+// parseSearchContinuation parses the videos and continuation of a search
+// continuation response.
+func parseSearchContinuation(data []byte) (*SearchPage, error) {
+	var response struct {
+		OnResponseReceivedCommands []struct {
+			AppendContinuationItemsAction struct {
+				ContinuationItems []searchSection `json:"continuationItems"`
+			} `json:"appendContinuationItemsAction"`
+		} `json:"onResponseReceivedCommands"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	sections := make([]searchSection, 0)
+	for _, command := range response.OnResponseReceivedCommands {
+		sections = append(sections, command.AppendContinuationItemsAction.ContinuationItems...)
+	}
+
+	return newSearchPage(sections), nil
+}
+
+func newSearchPage(sections []searchSection) *SearchPage {
+	page := &SearchPage{
+		Results: make([]SearchResult, 0),
+	}
+
+	for _, section := range sections {
+		for _, content := range section.ItemSectionRenderer.Contents {
+			// Some ids might be empty every now and then - filter these out
+			if content.VideoRenderer.VideoID == "" {
+				continue
 			}
-			results = append(results, SearchResult{
-				ID:    content.VideoRenderer.VideoID,
-				Title: title,
-			})
+
+			page.Results = append(page.Results, content.VideoRenderer.result())
+		}
+
+		if token := section.ContinuationItemRenderer.ContinuationEndpoint.ContinuationCommand.Token; token != "" {
+			page.Continuation = token
 		}
 	}
 
-	// Some ids might be empty every now and then - filter these out
-	results = slices.DeleteFunc(results, func(result SearchResult) bool {
-		return result.ID == ""
-	})
+	return page
+}
 
-	slog.Debug("Successfully performed search", slog.Int("results", len(results)))
-	return results, nil
+type text struct {
+	SimpleText string `json:"simpleText"`
+	Runs       []struct {
+		Text string `json:"text"`
+	} `json:"runs"`
+}
+
+// String returns the text, joining runs if there are any.
+func (t text) String() string {
+	if t.SimpleText != "" {
+		return t.SimpleText
+	}
+
+	var builder strings.Builder
+	for _, run := range t.Runs {
+		builder.WriteString(run.Text)
+	}
+	return builder.String()
+}
+
+type badge struct {
+	MetadataBadgeRenderer struct {
+		Style string `json:"style"`
+	} `json:"metadataBadgeRenderer"`
+}
+
+type videoRenderer struct {
+	VideoID       string `json:"videoId"`
+	Title         text   `json:"title"`
+	LengthText    text   `json:"lengthText"`
+	OwnerText     text   `json:"ownerText"`
+	ViewCountText text   `json:"viewCountText"`
+	Thumbnail     struct {
+		Thumbnails []struct {
+			URL    string `json:"url"`
+			Width  int    `json:"width"`
+			Height int    `json:"height"`
+		} `json:"thumbnails"`
+	} `json:"thumbnail"`
+	Badges             []badge `json:"badges"`
+	OwnerBadges        []badge `json:"ownerBadges"`
+	NavigationEndpoint struct {
+		CommandMetadata struct {
+			WebCommandMetadata struct {
+				URL string `json:"url"`
+			} `json:"webCommandMetadata"`
+		} `json:"commandMetadata"`
+	} `json:"navigationEndpoint"`
+}
+
+func (v videoRenderer) result() SearchResult {
+	result := SearchResult{
+		ID:      v.VideoID,
+		Title:   v.Title.String(),
+		Channel: v.OwnerText.String(),
+		Short:   strings.HasPrefix(v.NavigationEndpoint.CommandMetadata.WebCommandMetadata.URL, "/shorts/"),
+	}
+
+	// Livestreams have no length
+	result.Duration, _ = parseDuration(v.LengthText.String())
+	// Livestreams have a count of viewers rather than views
+	result.Views, _ = parseViewCount(v.ViewCountText.String())
+
+	for _, badge := range v.Badges {
+		if badge.MetadataBadgeRenderer.Style == "BADGE_STYLE_TYPE_LIVE_NOW" {
+			result.Live = true
+		}
+	}
+
+	for _, badge := range v.OwnerBadges {
+		switch badge.MetadataBadgeRenderer.Style {
+		case "BADGE_STYLE_TYPE_VERIFIED_ARTIST":
+			result.ChannelBadge = ChannelBadgeArtist
+		case "BADGE_STYLE_TYPE_VERIFIED":
+			result.ChannelBadge = max(result.ChannelBadge, ChannelBadgeVerified)
+		}
+	}
+
+	for _, thumbnail := range v.Thumbnail.Thumbnails {
+		result.Thumbnails = append(result.Thumbnails, Thumbnail{
+			URL:    thumbnail.URL,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+		})
+	}
+
+	return result
+}
+
+// Lookup looks up a video by its id. Returns false if the video could not be
+// found.
+func (c *SearchClient) Lookup(ctx context.Context, id string) (SearchResult, bool, error) {
+	// Searching for the id of a video returns the video
+	results, err := c.Search(ctx, id)
+	if err != nil {
+		return SearchResult{}, false, err
+	}
+
+	for _, result := range results {
+		if result.ID == id {
+			return result, true, nil
+		}
+	}
+
+	return SearchResult{}, false, nil
+}
+
+// innertubeClientVersion is the version of the web client to identify as when
+// using YouTube's internal API.
+const innertubeClientVersion = "2.20240726.00.00"
+
+// innertube performs a request to an endpoint of YouTube's internal API and
+// returns the raw JSON response.
+func (c *SearchClient) innertube(ctx context.Context, endpoint string, body map[string]any) ([]byte, error) {
+	body["context"] = map[string]any{
+		"client": map[string]any{
+			"clientName":    "WEB",
+			"clientVersion": innertubeClientVersion,
+			"hl":            "en",
+		},
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     "www.youtube.com",
+		Path:     "/youtubei/v1/" + endpoint,
+		RawQuery: url.Values{"prettyPrint": []string{"false"}}.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrTooManyRequests
+	} else if res.StatusCode != http.StatusOK {
+		slog.Error("Failed to perform API request", slog.String("endpoint", endpoint), slog.String("status", res.Status))
+		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// initialData fetches the page at the URL and returns the raw initial data
+// JSON embedded in it.
+func (c *SearchClient) initialData(ctx context.Context, u url.URL) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrTooManyRequests
+	} else if res.StatusCode != http.StatusOK {
+		slog.Error("Failed to fetch page", slog.String("path", u.Path), slog.String("status", res.Status))
+		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	// Read just as little as is requried to match the initial data
+	var buffer bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(res.Body, &buffer))
+	match := initialDataRegex.FindReaderIndex(reader)
+	if match == nil {
+		return nil, fmt.Errorf("unable to find initial data in response")
+	}
+
+	// Extract the match
+	return buffer.Bytes()[match[0]+20 : match[1]-1], nil
 }
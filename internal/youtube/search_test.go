@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,3 +18,77 @@ func TestSearchClient(t *testing.T) {
 
 	fmt.Printf("%+v\n", results)
 }
+
+func TestParseSearchPage(t *testing.T) {
+	data := `{"contents":{"twoColumnSearchResultsRenderer":{"primaryContents":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[
+		{"videoRenderer":{"videoId":"dQw4w9WgXcQ","title":{"runs":[{"text":"Never Gonna Give You Up"}]},"ownerText":{"runs":[{"text":"Rick Astley"}]},"lengthText":{"simpleText":"3:33"},"viewCountText":{"simpleText":"1,234,567 views"},"ownerBadges":[{"metadataBadgeRenderer":{"style":"BADGE_STYLE_TYPE_VERIFIED_ARTIST"}}],"thumbnail":{"thumbnails":[{"url":"https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg","width":480,"height":360}]},"navigationEndpoint":{"commandMetadata":{"webCommandMetadata":{"url":"/watch?v=dQw4w9WgXcQ"}}}}},
+		{"videoRenderer":{"videoId":"jfKfPfyJRdk","title":{"runs":[{"text":"lofi hip hop radio"}]},"ownerText":{"runs":[{"text":"Lofi Girl"}]},"viewCountText":{"runs":[{"text":"31,337"},{"text":" watching"}]},"badges":[{"metadataBadgeRenderer":{"style":"BADGE_STYLE_TYPE_LIVE_NOW"}}],"ownerBadges":[{"metadataBadgeRenderer":{"style":"BADGE_STYLE_TYPE_VERIFIED"}}]}},
+		{"videoRenderer":{"videoId":"BaW_jenozKc","title":{"runs":[{"text":"Short"}]},"lengthText":{"simpleText":"0:10"},"navigationEndpoint":{"commandMetadata":{"webCommandMetadata":{"url":"/shorts/BaW_jenozKc"}}}}},
+		{"videoRenderer":{}},
+		{"shelfRenderer":{}}
+	]}}]}}}}}`
+
+	page, err := parseSearchPage([]byte(data))
+	require.NoError(t, err)
+
+	expected := []SearchResult{
+		{
+			ID:           "dQw4w9WgXcQ",
+			Title:        "Never Gonna Give You Up",
+			Channel:      "Rick Astley",
+			Duration:     213 * time.Second,
+			Views:        1234567,
+			ChannelBadge: ChannelBadgeArtist,
+			Thumbnails: []Thumbnail{
+				{
+					URL:    "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg",
+					Width:  480,
+					Height: 360,
+				},
+			},
+		},
+		{
+			ID:           "jfKfPfyJRdk",
+			Title:        "lofi hip hop radio",
+			Channel:      "Lofi Girl",
+			Views:        31337,
+			Live:         true,
+			ChannelBadge: ChannelBadgeVerified,
+		},
+		{
+			ID:       "BaW_jenozKc",
+			Title:    "Short",
+			Duration: 10 * time.Second,
+			Short:    true,
+		},
+	}
+	assert.Equal(t, expected, page.Results)
+	assert.False(t, page.HasNext())
+}
+
+func TestParseSearchPageContinuation(t *testing.T) {
+	data := `{"contents":{"twoColumnSearchResultsRenderer":{"primaryContents":{"sectionListRenderer":{"contents":[
+		{"itemSectionRenderer":{"contents":[{"videoRenderer":{"videoId":"dQw4w9WgXcQ","title":{"runs":[{"text":"Never Gonna Give You Up"}]}}}]}},
+		{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"first"}}}}
+	]}}}}}`
+
+	page, err := parseSearchPage([]byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, []SearchResult{{ID: "dQw4w9WgXcQ", Title: "Never Gonna Give You Up"}}, page.Results)
+	assert.True(t, page.HasNext())
+	assert.Equal(t, "first", page.Continuation)
+}
+
+func TestParseSearchContinuation(t *testing.T) {
+	data := `{"onResponseReceivedCommands":[{"appendContinuationItemsAction":{"continuationItems":[
+		{"itemSectionRenderer":{"contents":[{"videoRenderer":{"videoId":"BaW_jenozKc","title":{"runs":[{"text":"youtube-dl test video"}]},"lengthText":{"simpleText":"0:10"}}}]}},
+		{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"second"}}}}
+	]}}]}`
+
+	page, err := parseSearchContinuation([]byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, []SearchResult{{ID: "BaW_jenozKc", Title: "youtube-dl test video", Duration: 10 * time.Second}}, page.Results)
+	assert.Equal(t, "second", page.Continuation)
+}
@@ -0,0 +1,60 @@
+package youtube
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// idRegex matches valid video ids.
+var idRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// IsVideoID returns whether or not id is a valid video id.
+func IsVideoID(id string) bool {
+	return idRegex.MatchString(id)
+}
+
+// URL describes a parsed YouTube URL.
+type URL struct {
+	// VideoID is the id of the linked video, if any.
+	VideoID string
+	// PlaylistID is the id of the linked playlist, if any. Set only for links
+	// to playlists, not for videos played as part of a playlist.
+	PlaylistID string
+}
+
+// ParseURL parses a YouTube URL. Supports links to videos, such as
+// youtube.com/watch?v=, youtu.be/, youtube.com/shorts/ and music.youtube.com,
+// and links to playlists, such as youtube.com/playlist?list=.
+func ParseURL(location string) (URL, bool) {
+	u, err := url.Parse(strings.TrimSpace(location))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return URL{}, false
+	}
+
+	var result URL
+	switch strings.TrimPrefix(u.Hostname(), "www.") {
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		path := strings.Trim(u.Path, "/")
+		switch {
+		case path == "watch":
+			result.VideoID = u.Query().Get("v")
+		case path == "playlist":
+			result.PlaylistID = u.Query().Get("list")
+		case strings.HasPrefix(path, "shorts/"), strings.HasPrefix(path, "live/"), strings.HasPrefix(path, "embed/"):
+			_, result.VideoID, _ = strings.Cut(path, "/")
+		}
+	case "youtu.be":
+		result.VideoID = strings.Trim(u.Path, "/")
+	}
+
+	if result.VideoID != "" && !idRegex.MatchString(result.VideoID) {
+		return URL{}, false
+	}
+
+	if result.VideoID == "" && result.PlaylistID == "" {
+		return URL{}, false
+	}
+
+	return result, true
+}
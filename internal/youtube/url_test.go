@@ -0,0 +1,71 @@
+package youtube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseURL(t *testing.T) {
+	testCases := []struct {
+		URL      string
+		Expected URL
+		OK       bool
+	}{
+		{
+			URL:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			Expected: URL{VideoID: "dQw4w9WgXcQ"},
+			OK:       true,
+		},
+		{
+			URL:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI",
+			Expected: URL{VideoID: "dQw4w9WgXcQ"},
+			OK:       true,
+		},
+		{
+			URL:      "https://youtu.be/dQw4w9WgXcQ?si=abc",
+			Expected: URL{VideoID: "dQw4w9WgXcQ"},
+			OK:       true,
+		},
+		{
+			URL:      "https://youtube.com/shorts/dQw4w9WgXcQ",
+			Expected: URL{VideoID: "dQw4w9WgXcQ"},
+			OK:       true,
+		},
+		{
+			URL:      "https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share",
+			Expected: URL{VideoID: "dQw4w9WgXcQ"},
+			OK:       true,
+		},
+		{
+			URL:      "https://m.youtube.com/watch?v=dQw4w9WgXcQ",
+			Expected: URL{VideoID: "dQw4w9WgXcQ"},
+			OK:       true,
+		},
+		{
+			URL:      "https://www.youtube.com/playlist?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI",
+			Expected: URL{PlaylistID: "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"},
+			OK:       true,
+		},
+		{
+			URL: "https://www.youtube.com/watch?v=invalid",
+		},
+		{
+			URL: "https://www.youtube.com/@RickAstleyYT",
+		},
+		{
+			URL: "https://example.com/watch?v=dQw4w9WgXcQ",
+		},
+		{
+			URL: "never gonna give you up",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.URL, func(t *testing.T) {
+			actual, ok := ParseURL(testCase.URL)
+			assert.Equal(t, testCase.OK, ok)
+			assert.Equal(t, testCase.Expected, actual)
+		})
+	}
+}
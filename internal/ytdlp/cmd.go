@@ -11,15 +11,49 @@ import (
 type Error struct {
 	ExitCode int
 	Stderr   string
+	// Err is the cause of the error, such as ErrPrivate, classified using
+	// Stderr. Nil if unknown.
+	Err error
 }
 
 func (e Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("yt-dlp: %s (exit code %d)", e.Err, e.ExitCode)
+	}
 	return fmt.Sprintf("yt-dlp: exit code %d", e.ExitCode)
 }
 
+// Unwrap returns the cause of the error, so that it can be matched using
+// errors.Is, such as errors.Is(err, ErrPrivate).
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns the cause of the error in a form suitable for labels of
+// metrics, such as "private". Returns "unknown" if the cause is unknown.
+func (e Error) Reason() string {
+	if reason, ok := reasons[e.Err]; ok {
+		return reason
+	}
+	return "unknown"
+}
+
 // Stream uses yt-dlp to stream opus audio in a webm container to w.
 func Stream(ctx context.Context, url string, w io.Writer) error {
-	cmd := exec.CommandContext(ctx, "yt-dlp", "--quiet", "--no-playlist", "-f", "ba[ext=webm][acodec=opus]", "-o", "-", url)
+	// URLs are given by users, so make sure they're never parsed as options
+	return run(ctx, w, "--quiet", "--no-playlist", "-f", "ba[ext=webm][acodec=opus]", "-o", "-", "--", url)
+}
+
+// StreamAny uses yt-dlp to stream the best available audio to w, in whichever
+// format it's available. Falls back to the best format holding both audio and
+// video for sites which don't provide audio on its own.
+func StreamAny(ctx context.Context, url string, w io.Writer) error {
+	return run(ctx, w, "--quiet", "--no-playlist", "-f", "ba/b", "-o", "-", "--", url)
+}
+
+// run runs yt-dlp with the args, writing its output to w.
+func run(ctx context.Context, w io.Writer, args ...string) error {
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
 
 	cmd.Stdout = w
 
@@ -27,9 +61,21 @@ func Stream(ctx context.Context, url string, w io.Writer) error {
 	cmd.Stderr = &buffer
 
 	if err := cmd.Run(); err != nil {
+		// Such as when yt-dlp is not installed
+		if cmd.ProcessState == nil {
+			return err
+		}
+
+		cause := classify(buffer.String())
+		// yt-dlp is killed when the context is done, such as when skipping
+		if ctx.Err() != nil {
+			cause = ErrCancelled
+		}
+
 		return Error{
 			ExitCode: cmd.ProcessState.ExitCode(),
 			Stderr:   buffer.String(),
+			Err:      cause,
 		}
 	}
 
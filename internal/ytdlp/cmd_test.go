@@ -0,0 +1,50 @@
+package ytdlp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamURLIsNotAnOption(t *testing.T) {
+	// Replace yt-dlp with a script printing its arguments
+	bin := t.TempDir()
+	script := "#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "yt-dlp"), []byte(script), 0755))
+	t.Setenv("PATH", bin)
+
+	testCases := []struct {
+		Name   string
+		Stream func(ctx context.Context, url string, w *bytes.Buffer) error
+	}{
+		{
+			Name: "Stream",
+			Stream: func(ctx context.Context, url string, w *bytes.Buffer) error {
+				return Stream(ctx, url, w)
+			},
+		},
+		{
+			Name: "StreamAny",
+			Stream: func(ctx context.Context, url string, w *bytes.Buffer) error {
+				return StreamAny(ctx, url, w)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			require.NoError(t, testCase.Stream(context.Background(), "--exec=touch pwned", &stdout))
+
+			args := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+			require.GreaterOrEqual(t, len(args), 2)
+			assert.Equal(t, []string{"--", "--exec=touch pwned"}, args[len(args)-2:])
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package ytdlp
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnavailable   = errors.New("video unavailable")
+	ErrPrivate       = errors.New("private video")
+	ErrAgeRestricted = errors.New("age restricted")
+	ErrGeoBlocked    = errors.New("geo blocked")
+	ErrNoFormat      = errors.New("no matching format")
+	ErrRateLimited   = errors.New("rate limited")
+	// ErrCancelled is the cause of errors of yt-dlp processes killed due to
+	// their context being done, such as when skipping a song.
+	ErrCancelled = errors.New("cancelled")
+)
+
+// classifications maps messages written by yt-dlp to the errors they cause.
+// The more specific errors come first, as YouTube prefixes most errors with
+// "Video unavailable".
+var classifications = []struct {
+	err      error
+	messages []string
+}{
+	{ErrRateLimited, []string{"http error 429", "too many requests", "confirm you're not a bot", "confirm you’re not a bot"}},
+	{ErrPrivate, []string{"private video", "video is private", "members-only"}},
+	{ErrAgeRestricted, []string{"confirm your age", "age-restricted", "age restricted", "inappropriate for some users"}},
+	{ErrGeoBlocked, []string{"not available in your country", "not made this video available in your country", "geo restriction", "geo-restricted", "from your location"}},
+	{ErrNoFormat, []string{"requested format is not available", "no video formats found"}},
+	{ErrUnavailable, []string{"video unavailable", "video is unavailable", "is not available", "has been removed", "been terminated", "http error 404", "does not exist"}},
+}
+
+// classify returns the cause of an error written by yt-dlp to stderr, or nil
+// if unknown. Warnings are ignored unless yt-dlp wrote no error.
+func classify(stderr string) error {
+	lines := make([]string, 0)
+	for line := range strings.Lines(stderr) {
+		if strings.HasPrefix(line, "ERROR:") {
+			lines = append(lines, line)
+		}
+	}
+
+	message := stderr
+	if len(lines) > 0 {
+		message = strings.Join(lines, "")
+	}
+	message = strings.ToLower(message)
+
+	for _, classification := range classifications {
+		for _, m := range classification.messages {
+			if strings.Contains(message, m) {
+				return classification.err
+			}
+		}
+	}
+
+	return nil
+}
+
+// reasons holds the reasons of the classified errors, used as labels of
+// metrics.
+var reasons = map[error]string{
+	ErrUnavailable:   "unavailable",
+	ErrPrivate:       "private",
+	ErrAgeRestricted: "age_restricted",
+	ErrGeoBlocked:    "geo_blocked",
+	ErrNoFormat:      "no_format",
+	ErrRateLimited:   "rate_limited",
+	ErrCancelled:     "cancelled",
+}
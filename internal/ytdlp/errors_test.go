@@ -0,0 +1,74 @@
+package ytdlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Stderr   string
+		Expected error
+	}{
+		{
+			Name:     "unavailable",
+			Stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable. This video has been removed by the uploader\n",
+			Expected: ErrUnavailable,
+		},
+		{
+			Name:     "private",
+			Stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Private video. Sign in if you've been granted access to this video\n",
+			Expected: ErrPrivate,
+		},
+		{
+			Name:     "age restricted",
+			Stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Sign in to confirm your age. This video may be inappropriate for some users.\n",
+			Expected: ErrAgeRestricted,
+		},
+		{
+			Name:     "geo blocked",
+			Stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable. The uploader has not made this video available in your country\n",
+			Expected: ErrGeoBlocked,
+		},
+		{
+			Name:     "no format",
+			Stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Requested format is not available. Use --list-formats for a list of available formats\n",
+			Expected: ErrNoFormat,
+		},
+		{
+			Name:     "rate limited",
+			Stderr:   "ERROR: [youtube] dQw4w9WgXcQ: Sign in to confirm you’re not a bot. Use --cookies-from-browser or --cookies for the authentication\n",
+			Expected: ErrRateLimited,
+		},
+		{
+			Name:     "warnings are ignored",
+			Stderr:   "WARNING: [youtube] Video unavailable in some formats\nERROR: unable to download video data: HTTP Error 429: Too Many Requests\n",
+			Expected: ErrRateLimited,
+		},
+		{
+			Name:     "unknown",
+			Stderr:   "ERROR: Unsupported URL: https://example.com\n",
+			Expected: nil,
+		},
+		{
+			Name:     "empty",
+			Stderr:   "",
+			Expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, classify(testCase.Stderr))
+		})
+	}
+}
+
+func TestErrorReason(t *testing.T) {
+	err := error(Error{ExitCode: 1, Err: ErrGeoBlocked})
+	assert.ErrorIs(t, err, ErrGeoBlocked)
+	assert.Equal(t, "geo_blocked", Error{Err: ErrGeoBlocked}.Reason())
+	assert.Equal(t, "unknown", Error{}.Reason())
+}
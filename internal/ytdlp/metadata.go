@@ -0,0 +1,237 @@
+package ytdlp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Info describes media, as resolved by yt-dlp.
+type Info struct {
+	ID    string
+	Title string
+	// Uploader is the name of the uploader, such as the artist of a SoundCloud
+	// track or the channel of a YouTube video.
+	Uploader string
+	Duration time.Duration
+	// Extractor is the name of the extractor that resolved the media, such as
+	// "Soundcloud" or "Bandcamp".
+	Extractor string
+	// URL is the URL of the media's web page.
+	URL string
+	// Live is true if the media is a livestream which is currently live.
+	Live bool
+	// AgeLimit is the age required to view the media. Zero if the media is not
+	// age restricted.
+	AgeLimit   int
+	Formats    []Format
+	Chapters   []Chapter
+	Thumbnails []Thumbnail
+}
+
+// Format is a format media is available in.
+type Format struct {
+	ID string
+	// Extension is the file extension of the format, such as "webm".
+	Extension string
+	// AudioCodec is the codec of the audio, such as "opus". Empty if the
+	// format holds no audio.
+	AudioCodec string
+	// VideoCodec is the codec of the video. Empty if the format holds no
+	// video.
+	VideoCodec string
+}
+
+// Chapter is a chapter of media, such as a song of an album uploaded as a
+// single video.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Thumbnail is a thumbnail of media. The width and height are zero if
+// unknown.
+type Thumbnail struct {
+	URL    string
+	Width  int
+	Height int
+}
+
+// HasOpus returns whether or not the media is available as opus audio in a
+// webm container without video, which is what Stream streams.
+func (i Info) HasOpus() bool {
+	for _, format := range i.Formats {
+		if format.Extension == "webm" && format.AudioCodec == "opus" && format.VideoCodec == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// info is the JSON representation of Info written by yt-dlp.
+type info struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Uploader string  `json:"uploader"`
+	Channel  string  `json:"channel"`
+	Duration float64 `json:"duration"`
+
+	Extractor string `json:"extractor_key"`
+	// IEKey is the extractor of entries of flat playlists.
+	IEKey string `json:"ie_key"`
+
+	WebpageURL string `json:"webpage_url"`
+	// URL is the URL of entries of flat playlists.
+	URL string `json:"url"`
+
+	IsLive   bool `json:"is_live"`
+	AgeLimit int  `json:"age_limit"`
+
+	Formats []struct {
+		ID         string `json:"format_id"`
+		Extension  string `json:"ext"`
+		AudioCodec string `json:"acodec"`
+		VideoCodec string `json:"vcodec"`
+	} `json:"formats"`
+
+	Chapters []struct {
+		Title     string  `json:"title"`
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+	} `json:"chapters"`
+
+	Thumbnails []struct {
+		URL    string `json:"url"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"thumbnails"`
+}
+
+// Info returns the info.
+func (m info) Info() Info {
+	result := Info{
+		ID:         m.ID,
+		Title:      m.Title,
+		Uploader:   m.Uploader,
+		Duration:   seconds(m.Duration),
+		Extractor:  m.Extractor,
+		URL:        m.WebpageURL,
+		Live:       m.IsLive,
+		AgeLimit:   m.AgeLimit,
+		Formats:    make([]Format, 0, len(m.Formats)),
+		Chapters:   make([]Chapter, 0, len(m.Chapters)),
+		Thumbnails: make([]Thumbnail, 0, len(m.Thumbnails)),
+	}
+
+	if result.Uploader == "" {
+		result.Uploader = m.Channel
+	}
+
+	if result.Extractor == "" {
+		result.Extractor = m.IEKey
+	}
+
+	if result.URL == "" {
+		result.URL = m.URL
+	}
+
+	for _, format := range m.Formats {
+		result.Formats = append(result.Formats, Format{
+			ID:         format.ID,
+			Extension:  format.Extension,
+			AudioCodec: codec(format.AudioCodec),
+			VideoCodec: codec(format.VideoCodec),
+		})
+	}
+
+	for _, chapter := range m.Chapters {
+		result.Chapters = append(result.Chapters, Chapter{
+			Title: chapter.Title,
+			Start: seconds(chapter.StartTime),
+			End:   seconds(chapter.EndTime),
+		})
+	}
+
+	for _, thumbnail := range m.Thumbnails {
+		result.Thumbnails = append(result.Thumbnails, Thumbnail{
+			URL:    thumbnail.URL,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+		})
+	}
+
+	return result
+}
+
+// seconds returns the duration of the seconds written by yt-dlp.
+func seconds(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// codec returns the codec, or an empty string if yt-dlp reports that there is
+// none.
+func codec(codec string) string {
+	if codec == "none" {
+		return ""
+	}
+	return codec
+}
+
+// Metadata uses yt-dlp to resolve the metadata of the media at url.
+// Playlists are not resolved, see Playlist.
+func Metadata(ctx context.Context, url string) (Info, error) {
+	var stdout bytes.Buffer
+	if err := run(ctx, &stdout, "--quiet", "--no-playlist", "--dump-json", "--", url); err != nil {
+		return Info{}, err
+	}
+
+	var result info
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return Info{}, err
+	}
+
+	return result.Info(), nil
+}
+
+// Playlist uses yt-dlp to resolve the entries of the playlist at url, such as
+// a Bandcamp album, up to limit entries. Links to single media resolve to a
+// single entry. Entries are resolved without their formats.
+func Playlist(ctx context.Context, url string, limit int) ([]Info, error) {
+	var stdout bytes.Buffer
+	if err := run(ctx, &stdout, "--quiet", "--flat-playlist", "--playlist-end", strconv.Itoa(limit), "--dump-json", "--", url); err != nil {
+		return nil, err
+	}
+
+	return parseInfoLines(stdout.Bytes())
+}
+
+// parseInfoLines parses info written by yt-dlp, one JSON object per line.
+func parseInfoLines(data []byte) ([]Info, error) {
+	results := make([]Info, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// The JSON of a single video may be several megabytes
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var result info
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result.Info())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
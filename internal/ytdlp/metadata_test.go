@@ -0,0 +1,57 @@
+package ytdlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInfoLines(t *testing.T) {
+	data := []byte(`{"id": "1", "title": "Song", "uploader": "Artist", "duration": 185.5, "extractor_key": "Soundcloud", "webpage_url": "https://soundcloud.com/artist/song", "is_live": false, "age_limit": 18, "formats": [{"format_id": "hls_opus", "ext": "opus", "acodec": "opus", "vcodec": "none"}, {"format_id": "http_mp3", "ext": "mp3", "acodec": "mp3", "vcodec": "none"}], "chapters": [{"title": "Intro", "start_time": 0, "end_time": 12.5}], "thumbnails": [{"url": "https://example.com/small.jpg", "width": 100, "height": 100}, {"url": "https://example.com/original.jpg"}]}
+
+{"_type": "url", "id": "2", "title": "Other Song", "channel": "Other Artist", "ie_key": "Bandcamp", "url": "https://artist.bandcamp.com/track/other-song"}
+`)
+
+	infos, err := parseInfoLines(data)
+	require.NoError(t, err)
+
+	expected := []Info{
+		{
+			ID:        "1",
+			Title:     "Song",
+			Uploader:  "Artist",
+			Duration:  185500 * time.Millisecond,
+			Extractor: "Soundcloud",
+			URL:       "https://soundcloud.com/artist/song",
+			AgeLimit:  18,
+			Formats: []Format{
+				{ID: "hls_opus", Extension: "opus", AudioCodec: "opus"},
+				{ID: "http_mp3", Extension: "mp3", AudioCodec: "mp3"},
+			},
+			Chapters: []Chapter{
+				{Title: "Intro", Start: 0, End: 12500 * time.Millisecond},
+			},
+			Thumbnails: []Thumbnail{
+				{URL: "https://example.com/small.jpg", Width: 100, Height: 100},
+				{URL: "https://example.com/original.jpg"},
+			},
+		},
+		{
+			ID:         "2",
+			Title:      "Other Song",
+			Uploader:   "Other Artist",
+			Extractor:  "Bandcamp",
+			URL:        "https://artist.bandcamp.com/track/other-song",
+			Formats:    []Format{},
+			Chapters:   []Chapter{},
+			Thumbnails: []Thumbnail{},
+		},
+	}
+	assert.Equal(t, expected, infos)
+
+	assert.False(t, infos[0].HasOpus())
+	assert.True(t, Info{Formats: []Format{{Extension: "webm", AudioCodec: "opus"}}}.HasOpus())
+	assert.False(t, Info{Formats: []Format{{Extension: "webm", AudioCodec: "opus", VideoCodec: "vp9"}}}.HasOpus())
+}